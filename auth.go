@@ -0,0 +1,226 @@
+package main
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-chi/render"
+)
+
+// tokenTTL is how long an access token is valid, refresh tokens are used
+// to get a new one after it expires
+const tokenTTL = 15 * time.Minute
+
+type contextKey string
+
+const (
+	userIDKey    contextKey = "userID"
+	sessionIDKey contextKey = "sessionID"
+)
+
+var errInvalidCredentials = newError(KindForbidden, CodeInvalidCredentials, "invalid username or password")
+
+// LoginRequest represents a request to the login route
+type LoginRequest struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+}
+
+// RefreshRequest represents a request to exchange a refresh token
+type RefreshRequest struct {
+	RefreshToken string `json:"refreshToken"`
+}
+
+// TokenResponse represents a response from the login and refresh routes
+type TokenResponse struct {
+	Token        string `json:"token"`
+	RefreshToken string `json:"refreshToken"`
+	UserID       int64  `json:"userId"`
+	ExpiresAt    int64  `json:"expiresAt"`
+}
+
+// tokenClaims is the signed payload of an access token
+type tokenClaims struct {
+	UserID    int64  `json:"uid"`
+	SessionID string `json:"sid"`
+	ExpiresAt int64  `json:"exp"`
+}
+
+// Bind allows for preprocessing of login requests
+func (l *LoginRequest) Bind(r *http.Request) error {
+	v := &ValidationError{}
+	if l.Username == "" {
+		v.Add("username", "is required")
+	}
+	if l.Password == "" {
+		v.Add("password", "is required")
+	}
+	return v.Err()
+}
+
+// Bind allows for preprocessing of refresh requests
+func (rr *RefreshRequest) Bind(r *http.Request) error {
+	if rr.RefreshToken == "" {
+		v := &ValidationError{}
+		v.Add("refreshToken", "is required")
+		return v
+	}
+	return nil
+}
+
+// Render allows for preprocessing of token response
+func (tr *TokenResponse) Render(w http.ResponseWriter, r *http.Request) error {
+	return nil
+}
+
+// newTokenResponse creates an access token for session and pairs it with refreshToken
+func (s *Server) newTokenResponse(session *Session, refreshToken string) (*TokenResponse, error) {
+	expires := time.Now().Add(tokenTTL)
+	token, err := s.newToken(session.UserID, session.ID, expires)
+	if err != nil {
+		return nil, err
+	}
+	return &TokenResponse{
+		Token:        token,
+		RefreshToken: refreshToken,
+		UserID:       session.UserID,
+		ExpiresAt:    expires.Unix(),
+	}, nil
+}
+
+// newToken creates a signed access token for userID in sessionID
+func (s *Server) newToken(userID int64, sessionID string, expires time.Time) (string, error) {
+	payload, err := json.Marshal(&tokenClaims{UserID: userID, SessionID: sessionID, ExpiresAt: expires.Unix()})
+	if err != nil {
+		return "", err
+	}
+	encoded := base64.RawURLEncoding.EncodeToString(payload)
+	return encoded + "." + s.signToken(encoded), nil
+}
+
+func (s *Server) signToken(payload string) string {
+	mac := hmac.New(sha256.New, s.tokenSecret)
+	mac.Write([]byte(payload))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// parseToken verifies the signature and expiry of a token and returns its claims
+func (s *Server) parseToken(token string) (*tokenClaims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 2 {
+		return nil, errors.New("malformed token")
+	}
+	if !hmac.Equal([]byte(s.signToken(parts[0])), []byte(parts[1])) {
+		return nil, errors.New("invalid token signature")
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return nil, errors.New("malformed token")
+	}
+	claims := &tokenClaims{}
+	if err := json.Unmarshal(payload, claims); err != nil {
+		return nil, errors.New("malformed token")
+	}
+	if time.Now().Unix() >= claims.ExpiresAt {
+		return nil, errors.New("token expired")
+	}
+	return claims, nil
+}
+
+// Authenticate validates the bearer token if one is present and adds the
+// authenticated user and session IDs to the request context
+func (s *Server) Authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header := r.Header.Get("Authorization")
+		if header == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if !strings.HasPrefix(header, "Bearer ") {
+			render.Render(w, r, ErrUnauthorized(errors.New("authorization header must be a bearer token")))
+			return
+		}
+		claims, err := s.parseToken(strings.TrimPrefix(header, "Bearer "))
+		if err != nil {
+			render.Render(w, r, ErrUnauthorized(err))
+			return
+		}
+		active, err := s.users.SessionActive(claims.SessionID, claims.UserID)
+		if err != nil {
+			render.Render(w, r, ErrDB(err))
+			return
+		}
+		if !active {
+			render.Render(w, r, ErrUnauthorized(errors.New("session revoked")))
+			return
+		}
+		ctx := context.WithValue(r.Context(), userIDKey, claims.UserID)
+		ctx = context.WithValue(ctx, sessionIDKey, claims.SessionID)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// RequireAuth rejects requests that were not authenticated by Authenticate
+func RequireAuth(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := UserIDFromContext(r.Context()); !ok {
+			render.Render(w, r, ErrUnauthorized(errors.New("authentication required")))
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// UserIDFromContext returns the authenticated user ID stored by Authenticate
+func UserIDFromContext(ctx context.Context) (int64, bool) {
+	id, ok := ctx.Value(userIDKey).(int64)
+	return id, ok
+}
+
+// SessionIDFromContext returns the session ID of the access token stored by Authenticate
+func SessionIDFromContext(ctx context.Context) (string, bool) {
+	id, ok := ctx.Value(sessionIDKey).(string)
+	return id, ok
+}
+
+// protectedID returns the authenticated user ID for a request or -1 if there is none
+func protectedID(r *http.Request) int64 {
+	if id, ok := UserIDFromContext(r.Context()); ok {
+		return id
+	}
+	return -1
+}
+
+// checkCredentials verifies a username and password, rehashing the stored
+// password if it is plaintext or was hashed with an old cost
+func (s *Server) checkCredentials(username, password string) (int64, error) {
+	id, stored, err := s.users.GetCredentials(username)
+	if err != nil {
+		if err == errUserNotFound {
+			return 0, errInvalidCredentials
+		}
+		return 0, err
+	}
+	ok, rehash := s.rules.Passwords.Verify(stored, password)
+	if !ok {
+		return 0, errInvalidCredentials
+	}
+	if rehash {
+		hash, err := s.rules.Passwords.Hash(password)
+		if err == nil {
+			err = s.users.SetPassword(id, hash)
+		}
+		if err != nil {
+			log.Printf("could not rehash password for user %d: %v", id, err)
+		}
+	}
+	return id, nil
+}
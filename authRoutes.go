@@ -0,0 +1,86 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/go-chi/chi"
+	"github.com/go-chi/render"
+)
+
+// AuthRoutes returns a router with authentication routes to be mounted in routes.go
+func (s *Server) AuthRoutes() *chi.Mux {
+	r := chi.NewRouter()
+	r.Post("/login", s.Login)
+	r.Post("/refresh", s.Refresh)
+	r.With(RequireAuth).Post("/logout", s.Logout)
+	return r
+}
+
+// Login verifies a username and password and starts a new session
+func (s *Server) Login(w http.ResponseWriter, r *http.Request) {
+	data := &LoginRequest{}
+	if err := render.Bind(r, data); err != nil {
+		render.Render(w, r, ErrBadRequest(err))
+		return
+	}
+	userID, err := s.checkCredentials(data.Username, data.Password)
+	if err != nil {
+		if err == errInvalidCredentials {
+			render.Render(w, r, ErrUnauthorized(err))
+			return
+		}
+		render.Render(w, r, ErrDB(err))
+		return
+	}
+	session, refreshToken, err := s.users.NewSession(userID, r.UserAgent())
+	if err != nil {
+		render.Render(w, r, ErrDB(err))
+		return
+	}
+	resp, err := s.newTokenResponse(session, refreshToken)
+	if err != nil {
+		render.Render(w, r, ErrRender(err))
+		return
+	}
+	render.Render(w, r, resp)
+}
+
+// Refresh rotates a refresh token and issues a new access token for its session
+func (s *Server) Refresh(w http.ResponseWriter, r *http.Request) {
+	data := &RefreshRequest{}
+	if err := render.Bind(r, data); err != nil {
+		render.Render(w, r, ErrBadRequest(err))
+		return
+	}
+	session, refreshToken, err := s.users.RotateSession(data.RefreshToken)
+	if err != nil {
+		if err == errSessionInvalid || err == errSessionReused {
+			render.Render(w, r, ErrUnauthorized(err))
+			return
+		}
+		render.Render(w, r, ErrDB(err))
+		return
+	}
+	resp, err := s.newTokenResponse(session, refreshToken)
+	if err != nil {
+		render.Render(w, r, ErrRender(err))
+		return
+	}
+	render.Render(w, r, resp)
+}
+
+// Logout revokes the session of the access token used for the request
+func (s *Server) Logout(w http.ResponseWriter, r *http.Request) {
+	sessionID, ok := SessionIDFromContext(r.Context())
+	if !ok {
+		render.Render(w, r, ErrUnauthorized(errors.New("authentication required")))
+		return
+	}
+	err := s.users.RevokeSession(sessionID, protectedID(r))
+	if err != nil && err != errSessionMissing {
+		render.Render(w, r, ErrDB(err))
+		return
+	}
+	render.NoContent(w, r)
+}
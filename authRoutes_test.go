@@ -0,0 +1,53 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestRefreshRotatesToken(t *testing.T) {
+	ts := newTestServer(t)
+	userID, login := ts.signup("player")
+
+	var refreshed TokenResponse
+	status := ts.do("POST", "/auth/refresh", "", map[string]string{"refreshToken": login.RefreshToken}, &refreshed)
+	if status != http.StatusOK {
+		t.Fatalf("refresh: status %d", status)
+	}
+	if refreshed.UserID != userID || refreshed.RefreshToken == login.RefreshToken {
+		t.Errorf("refreshed = %+v, want a new refresh token for user %d", refreshed, userID)
+	}
+	status = ts.do("POST", "/auth/refresh", "", map[string]string{"refreshToken": refreshed.RefreshToken}, nil)
+	if status != http.StatusOK {
+		t.Errorf("refresh with rotated token: status %d", status)
+	}
+}
+
+func TestRefreshReuseRevokesSession(t *testing.T) {
+	ts := newTestServer(t)
+	_, login := ts.signup("player")
+
+	var refreshed TokenResponse
+	if status := ts.do("POST", "/auth/refresh", "", map[string]string{"refreshToken": login.RefreshToken}, &refreshed); status != http.StatusOK {
+		t.Fatalf("refresh: status %d", status)
+	}
+	if status := ts.do("POST", "/auth/refresh", "", map[string]string{"refreshToken": login.RefreshToken}, nil); status != http.StatusUnauthorized {
+		t.Errorf("reusing a rotated token: status %d, want %d", status, http.StatusUnauthorized)
+	}
+	// reuse means the token may have been stolen, so the whole session is revoked
+	if status := ts.do("POST", "/auth/refresh", "", map[string]string{"refreshToken": refreshed.RefreshToken}, nil); status != http.StatusUnauthorized {
+		t.Errorf("refresh after reuse: status %d, want %d", status, http.StatusUnauthorized)
+	}
+	if status := ts.do("POST", "/team", refreshed.Token, map[string]string{"name": "Alpha Squad"}, nil); status != http.StatusUnauthorized {
+		t.Errorf("access token after reuse: status %d, want %d", status, http.StatusUnauthorized)
+	}
+}
+
+func TestLoginRejectsWrongPassword(t *testing.T) {
+	ts := newTestServer(t)
+	ts.signup("player")
+	login := map[string]string{"username": "player", "password": "not the password"}
+	if status := ts.do("POST", "/auth/login", "", login, nil); status != http.StatusUnauthorized {
+		t.Errorf("status = %d, want %d", status, http.StatusUnauthorized)
+	}
+}
@@ -0,0 +1,42 @@
+// Command riotfake serves a fake Riot API so accounts can be registered without an api key
+//
+// point lss-api at it with riotbaseurl=http://localhost:8089/{platform}
+// faults can be changed while it runs with PUT /riotfake/faults and the code a summoner
+// has set in the client with PUT /riotfake/{platform}/third-party-code/{summonerId}
+// and its ranked standings with PUT /riotfake/{platform}/leagues/{summonerId}
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+
+	"github.com/anthonyrouseau/lss-api/riotfake"
+)
+
+func main() {
+	addr := flag.String("addr", ":8089", "address to listen on")
+	fixturePath := flag.String("fixture", "", "json fixture to serve, the built in fixture when empty")
+	apiKey := flag.String("apikey", "", "api key required in X-Riot-Token, any key is accepted when empty")
+	latency := flag.Duration("latency", 0, "delay added to every response")
+	notFound := flag.Float64("notfound", 0, "fraction of requests answered with 404")
+	rateLimit := flag.Float64("ratelimit", 0, "fraction of requests answered with 429")
+	retryAfter := flag.Int("retryafter", 1, "Retry-After of 429 responses in seconds")
+	flag.Parse()
+
+	fixture, err := riotfake.LoadFixture(*fixturePath)
+	if err != nil {
+		log.Fatal(err)
+	}
+	s := riotfake.NewServer(fixture)
+	s.APIKey = *apiKey
+	s.SetFaults(riotfake.Faults{
+		Latency:       *latency,
+		NotFoundRate:  *notFound,
+		RateLimitRate: *rateLimit,
+		RetryAfter:    *retryAfter,
+	})
+	fmt.Println("riotfake listening on " + *addr)
+	log.Fatal(http.ListenAndServe(*addr, s))
+}
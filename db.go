@@ -0,0 +1,277 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"log"
+	"net"
+	"strconv"
+	"strings"
+)
+
+// SQLStore implements UserStore, TeamStore and InviteStore on a SQL database
+// queries are written once and adjusted for the database by its dialect
+type SQLStore struct {
+	db *sqlDB
+}
+
+// NewSQLStore creates a SQLStore using db, which must have been opened with the driver of d
+func NewSQLStore(db *sql.DB, d *dialect) *SQLStore {
+	return &SQLStore{db: &sqlDB{DB: db, dialect: d}}
+}
+
+// dialect holds what differs between the databases a SQLStore can use
+// queries are written with ? placeholders, {startswith} for a prefix match,
+// {usernameis} to look up an account by username and {forupdate} after a SELECT
+// whose rows stay locked until the transaction ends
+type dialect struct {
+	// driver is the database/sql driver name
+	driver string
+	// startsWith matches a column against values starting with the bound parameter
+	startsWith string
+	// usernameIs matches account.username against the bound parameter ignoring case,
+	// written so the unique index on usernames can be used
+	usernameIs string
+	// forUpdate locks the rows read by a SELECT inside a transaction, it is empty
+	// where a write transaction already holds the whole database
+	forUpdate string
+	// numberedParams is set when placeholders are written $1, $2, ...
+	numberedParams bool
+	// returningID is set when new row ids are read with RETURNING id instead of LastInsertId
+	returningID bool
+	// classify converts a driver error to a *DBError or returns nil
+	classify func(err error) *DBError
+	// transactionalDDL is set when schema changes are rolled back with the transaction
+	// they ran in, mysql commits each one as it runs so a failed migration is left half applied
+	transactionalDDL bool
+	// rebuildsTables is set when columns can only be dropped by rebuilding a table,
+	// migrations then run with foreign keys off and are checked before they commit
+	rebuildsTables bool
+}
+
+// dialects are the databases a SQLStore can be opened on, by store name
+var dialects = map[string]*dialect{
+	"mysql":    mysqlDialect,
+	"sqlite":   sqliteDialect,
+	"postgres": postgresDialect,
+}
+
+// rewrite adjusts a query written for the ? placeholder style to the dialect
+func (d *dialect) rewrite(query string) string {
+	query = strings.Replace(query, "{startswith}", d.startsWith, -1)
+	query = strings.Replace(query, "{usernameis}", d.usernameIs, -1)
+	query = strings.Replace(query, "{forupdate}", d.forUpdate, -1)
+	if !d.numberedParams {
+		return query
+	}
+	var b strings.Builder
+	n := 0
+	for _, c := range query {
+		if c == '?' {
+			n++
+			b.WriteString("$" + strconv.Itoa(n))
+			continue
+		}
+		b.WriteRune(c)
+	}
+	return b.String()
+}
+
+// insertID runs an INSERT statement on p and returns the id of the new row
+func (d *dialect) insertID(p preparer, query string, args ...interface{}) (int64, error) {
+	if d.returningID {
+		stmt, err := p.Prepare(query + " RETURNING id")
+		if err != nil {
+			return 0, err
+		}
+		var id int64
+		err = stmt.QueryRow(args...).Scan(&id)
+		return id, err
+	}
+	stmt, err := p.Prepare(query)
+	if err != nil {
+		return 0, err
+	}
+	res, err := stmt.Exec(args...)
+	if err != nil {
+		return 0, err
+	}
+	return res.LastInsertId()
+}
+
+// sqlDB wraps a *sql.DB and rewrites queries for its dialect before running them
+type sqlDB struct {
+	*sql.DB
+	dialect *dialect
+}
+
+// Exec runs a query that returns no rows
+func (db *sqlDB) Exec(query string, args ...interface{}) (sql.Result, error) {
+	return db.DB.Exec(db.dialect.rewrite(query), args...)
+}
+
+// Query runs a query that returns rows
+func (db *sqlDB) Query(query string, args ...interface{}) (*sql.Rows, error) {
+	return db.DB.Query(db.dialect.rewrite(query), args...)
+}
+
+// QueryRow runs a query that returns at most one row
+func (db *sqlDB) QueryRow(query string, args ...interface{}) *sql.Row {
+	return db.DB.QueryRow(db.dialect.rewrite(query), args...)
+}
+
+// Prepare creates a prepared statement
+func (db *sqlDB) Prepare(query string) (*sql.Stmt, error) {
+	return db.DB.Prepare(db.dialect.rewrite(query))
+}
+
+// Begin starts a transaction that rewrites queries the same way
+func (db *sqlDB) Begin() (*sqlTx, error) {
+	tx, err := db.DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+	return &sqlTx{Tx: tx, dialect: db.dialect}, nil
+}
+
+func (db *sqlDB) insertID(query string, args ...interface{}) (int64, error) {
+	return db.dialect.insertID(db, query, args...)
+}
+
+// sqlTx wraps a *sql.Tx and rewrites queries for its dialect before running them
+type sqlTx struct {
+	*sql.Tx
+	dialect *dialect
+}
+
+// Exec runs a query that returns no rows
+func (tx *sqlTx) Exec(query string, args ...interface{}) (sql.Result, error) {
+	return tx.Tx.Exec(tx.dialect.rewrite(query), args...)
+}
+
+// Query runs a query that returns rows
+func (tx *sqlTx) Query(query string, args ...interface{}) (*sql.Rows, error) {
+	return tx.Tx.Query(tx.dialect.rewrite(query), args...)
+}
+
+// QueryRow runs a query that returns at most one row
+func (tx *sqlTx) QueryRow(query string, args ...interface{}) *sql.Row {
+	return tx.Tx.QueryRow(tx.dialect.rewrite(query), args...)
+}
+
+// Prepare creates a prepared statement
+func (tx *sqlTx) Prepare(query string) (*sql.Stmt, error) {
+	return tx.Tx.Prepare(tx.dialect.rewrite(query))
+}
+
+func (tx *sqlTx) insertID(query string, args ...interface{}) (int64, error) {
+	return tx.dialect.insertID(tx, query, args...)
+}
+
+// DBError is a driver error classified so handlers don't depend on the driver in use
+// Code is the driver's own error code
+type DBError struct {
+	Kind    ErrorKind
+	Code    string
+	Message string
+	Err     error
+}
+
+func (e *DBError) Error() string {
+	return e.Message
+}
+
+// Unwrap allows errors.As to reach the driver error
+func (e *DBError) Unwrap() error {
+	return e.Err
+}
+
+// asDBError finds a database error in err's chain and classifies it
+func asDBError(err error) (*DBError, bool) {
+	var dbErr *DBError
+	if errors.As(err, &dbErr) {
+		return dbErr, true
+	}
+	for _, d := range dialects {
+		if dbErr := d.classify(err); dbErr != nil {
+			return dbErr, true
+		}
+	}
+	return nil, false
+}
+
+// kindOf classifies err for the client, errors without a kind of their own are
+// classified from the driver error, missing rows and connection failures
+func kindOf(err error) ErrorKind {
+	var e *Error
+	if errors.As(err, &e) {
+		return e.Kind
+	}
+	if dbErr, ok := asDBError(err); ok {
+		return dbErr.Kind
+	}
+	if errors.Is(err, sql.ErrNoRows) {
+		return KindNotFound
+	}
+	if errors.Is(err, context.DeadlineExceeded) || errors.Is(err, context.Canceled) ||
+		errors.Is(err, driver.ErrBadConn) || errors.Is(err, sql.ErrConnDone) {
+		return KindUnavailable
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return KindUnavailable
+	}
+	return KindInternal
+}
+
+var (
+	_ UserStore   = (*SQLStore)(nil)
+	_ TeamStore   = (*SQLStore)(nil)
+	_ InviteStore = (*SQLStore)(nil)
+)
+
+// preparer is satisfied by both *sqlDB and *sqlTx so statements can
+// run inside or outside a transaction
+type preparer interface {
+	Prepare(query string) (*sql.Stmt, error)
+	insertID(query string, args ...interface{}) (int64, error)
+}
+
+// TxError is returned when a multi-step operation fails and its transaction is rolled back
+// Op names the operation and Err is the error that caused the rollback
+type TxError struct {
+	Op  string
+	Err error
+}
+
+func (e *TxError) Error() string {
+	return e.Op + ": " + e.Err.Error()
+}
+
+// Unwrap allows errors.Is and errors.As to see the cause of the rollback
+func (e *TxError) Unwrap() error {
+	return e.Err
+}
+
+// withTx runs fn inside a transaction, committing if it returns nil and rolling back otherwise
+// any failure is returned as a *TxError
+func (m *SQLStore) withTx(op string, fn func(tx *sqlTx) error) error {
+	tx, err := m.db.Begin()
+	if err != nil {
+		return &TxError{Op: op, Err: err}
+	}
+	err = fn(tx)
+	if err != nil {
+		if rbErr := tx.Rollback(); rbErr != nil {
+			log.Printf("%s: rollback failed: %v", op, rbErr)
+		}
+		return &TxError{Op: op, Err: err}
+	}
+	err = tx.Commit()
+	if err != nil {
+		return &TxError{Op: op, Err: err}
+	}
+	return nil
+}
@@ -1,85 +1,314 @@
 package main
 
 import (
+	"errors"
+	"log"
 	"net/http"
+	"strings"
 
+	"github.com/anthonyrouseau/lss-api/riot"
 	"github.com/go-chi/render"
-	"github.com/go-sql-driver/mysql"
 )
 
-// ErrResponse represents an error to be sent to the client
+// ErrorKind is the category of an error, it decides the status sent to the client
+type ErrorKind int
+
+// kinds of error, KindInternal is the zero value so unclassified errors are internal
+const (
+	KindInternal ErrorKind = iota
+	KindNotFound
+	KindConflict
+	KindValidation
+	KindForbidden
+	KindUnavailable
+)
+
+// Status returns the http status and status text for errors of kind k
+func (k ErrorKind) Status() (int, string) {
+	switch k {
+	case KindNotFound:
+		return 404, "Not Found"
+	case KindConflict:
+		return 409, "Conflict"
+	case KindValidation:
+		return 422, "Validation Failed"
+	case KindForbidden:
+		return 403, "Forbidden"
+	case KindUnavailable:
+		return 503, "Service Unavailable"
+	default:
+		return 500, "Internal Error"
+	}
+}
+
+// ErrorCode is a stable identifier for an error that clients can rely on
+// codes are never renamed or reused once released
+type ErrorCode string
+
+// generic codes used when an error has no code of its own
+const (
+	CodeBadRequest       ErrorCode = "BAD_REQUEST"
+	CodeValidationFailed ErrorCode = "VALIDATION_FAILED"
+	CodeUnauthorized     ErrorCode = "UNAUTHORIZED"
+	CodeForbidden        ErrorCode = "FORBIDDEN"
+	CodeNotFound         ErrorCode = "NOT_FOUND"
+	CodeConflict         ErrorCode = "CONFLICT"
+	CodeGone             ErrorCode = "GONE"
+	CodeInternal         ErrorCode = "INTERNAL_ERROR"
+	CodeUnavailable      ErrorCode = "SERVICE_UNAVAILABLE"
+	CodeRenderFailed     ErrorCode = "RENDER_FAILED"
+	CodeDuplicateEntry   ErrorCode = "DUPLICATE_ENTRY"
+	CodeInvalidReference ErrorCode = "INVALID_REFERENCE"
+)
+
+// codes of specific errors
+const (
+	CodeInvalidCredentials       ErrorCode = "INVALID_CREDENTIALS"
+	CodeSessionInvalid           ErrorCode = "SESSION_INVALID"
+	CodeSessionReused            ErrorCode = "SESSION_REUSED"
+	CodeSessionNotFound          ErrorCode = "SESSION_NOT_FOUND"
+	CodeUserNotFound             ErrorCode = "USER_NOT_FOUND"
+	CodeAccountLinkFailed        ErrorCode = "ACCOUNT_LINK_FAILED"
+	CodeSummonerNotFound         ErrorCode = "SUMMONER_NOT_FOUND"
+	CodeSummonerCodeMismatch     ErrorCode = "SUMMONER_CODE_MISMATCH"
+	CodeSummonerAlreadyLinked    ErrorCode = "SUMMONER_ALREADY_LINKED"
+	CodeSummonerNotLinked        ErrorCode = "SUMMONER_NOT_LINKED"
+	CodeVerificationNotFound     ErrorCode = "SUMMONER_VERIFICATION_NOT_FOUND"
+	CodeVerificationExpired      ErrorCode = "SUMMONER_VERIFICATION_EXPIRED"
+	CodeLinkedAccountNotFound    ErrorCode = "LINKED_ACCOUNT_NOT_FOUND"
+	CodeRiotRateLimited          ErrorCode = "RIOT_RATE_LIMITED"
+	CodeRiotUnavailable          ErrorCode = "RIOT_UNAVAILABLE"
+	CodeOffsetInvalid            ErrorCode = "OFFSET_INVALID"
+	CodeTeamNotFound             ErrorCode = "TEAM_NOT_FOUND"
+	CodeTeamCaptainCannotLeave   ErrorCode = "TEAM_CAPTAIN_CANNOT_LEAVE"
+	CodeTeamActionInvalid        ErrorCode = "TEAM_ACTION_INVALID"
+	CodeTeamNotCaptain           ErrorCode = "TEAM_NOT_CAPTAIN"
+	CodeTeamAlreadyCaptain       ErrorCode = "TEAM_ALREADY_CAPTAIN"
+	CodeRosterMemberNotFound     ErrorCode = "ROSTER_MEMBER_NOT_FOUND"
+	CodeRosterRoleInvalid        ErrorCode = "ROSTER_ROLE_INVALID"
+	CodeRosterMemberExists       ErrorCode = "ROSTER_MEMBER_EXISTS"
+	CodeInviteNotFound           ErrorCode = "INVITE_NOT_FOUND"
+	CodeInviteAlreadyExists      ErrorCode = "INVITE_ALREADY_EXISTS"
+	CodeInviteExpired            ErrorCode = "INVITE_EXPIRED"
+	CodeJoinLinkNotFound         ErrorCode = "JOIN_LINK_NOT_FOUND"
+	CodeJoinLinkInvalid          ErrorCode = "JOIN_LINK_INVALID"
+	CodeJoinRequestNotFound      ErrorCode = "JOIN_REQUEST_NOT_FOUND"
+	CodeJoinRequestAlreadyExists ErrorCode = "JOIN_REQUEST_ALREADY_EXISTS"
+	CodeJoinRequestLimit         ErrorCode = "JOIN_REQUEST_LIMIT_REACHED"
+	CodeJoinRequestsClosed       ErrorCode = "JOIN_REQUESTS_CLOSED"
+)
+
+// problemType returns the type URI reference of problems with code
+func problemType(code ErrorCode) string {
+	return "/errors/" + strings.ToLower(strings.Replace(string(code), "_", "-", -1))
+}
+
+// Error is an error the data layer has classified by kind and given a stable code
+// its message is safe to show to clients
+type Error struct {
+	Kind ErrorKind
+	Code ErrorCode
+	Err  error
+}
+
+func (e *Error) Error() string {
+	return e.Err.Error()
+}
+
+// Unwrap allows errors.Is and errors.As to see the underlying error
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// newError creates an error of kind with code and message, for use as a sentinel
+func newError(kind ErrorKind, code ErrorCode, message string) error {
+	return &Error{Kind: kind, Code: code, Err: errors.New(message)}
+}
+
+// FieldError describes why one field of a request is not valid
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// ValidationError collects every field error of a request so they can be reported at once
+type ValidationError struct {
+	Fields []FieldError
+}
+
+// Add records that field is not valid
+func (v *ValidationError) Add(field, message string) {
+	v.Fields = append(v.Fields, FieldError{Field: field, Message: message})
+}
+
+// Err returns v if any field was not valid and nil otherwise
+func (v *ValidationError) Err() error {
+	if len(v.Fields) == 0 {
+		return nil
+	}
+	return v
+}
+
+func (v *ValidationError) Error() string {
+	messages := make([]string, 0, len(v.Fields))
+	for _, f := range v.Fields {
+		messages = append(messages, f.Field+": "+f.Message)
+	}
+	return strings.Join(messages, ", ")
+}
+
+// ErrResponse represents an error sent to the client as RFC 7807 problem details
 type ErrResponse struct {
-	Err            error  `json:"-"`
-	HTTPStatusCode int    `json:"-"`
-	StatusText     string `json:"status"`
-	AppCode        uint16 `json:"code,omitempty"`
-	ErrorText      string `json:"error,omitempty"`
+	Err      error        `json:"-"`
+	Type     string       `json:"type"`
+	Title    string       `json:"title"`
+	Status   int          `json:"status"`
+	Detail   string       `json:"detail,omitempty"`
+	Instance string       `json:"instance,omitempty"`
+	Code     ErrorCode    `json:"code"`
+	Errors   []FieldError `json:"errors,omitempty"`
 }
 
 // Render provides preprocessing before error response sent to client
 func (e *ErrResponse) Render(w http.ResponseWriter, r *http.Request) error {
-	render.Status(r, e.HTTPStatusCode)
+	e.Instance = r.URL.RequestURI()
+	render.Status(r, e.Status)
 	return nil
 }
 
+// ProblemJSON is middleware that sends json error responses, which are all
+// rendered from an ErrResponse, as application/problem+json
+func ProblemJSON(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		next.ServeHTTP(&problemWriter{ResponseWriter: w}, r)
+	})
+}
+
+// problemWriter changes the content type of json responses with an error status
+type problemWriter struct {
+	http.ResponseWriter
+}
+
+// WriteHeader sets the problem+json content type before sending an error status
+func (pw *problemWriter) WriteHeader(status int) {
+	if status >= http.StatusBadRequest && strings.HasPrefix(pw.Header().Get("Content-Type"), "application/json") {
+		pw.Header().Set("Content-Type", "application/problem+json")
+	}
+	pw.ResponseWriter.WriteHeader(status)
+}
+
+// newErrResponse creates an error response, taking the code and detail from err
+// when it is an *Error and falling back to code and err's message otherwise
+func newErrResponse(err error, status int, title string, code ErrorCode) *ErrResponse {
+	var e *Error
+	if errors.As(err, &e) && e.Code != "" {
+		code = e.Code
+	}
+	return &ErrResponse{
+		Err:    err,
+		Type:   problemType(code),
+		Title:  title,
+		Status: status,
+		Detail: err.Error(),
+		Code:   code,
+	}
+}
+
 // ErrBadRequest is a Bad Request error
+// field errors from Bind are reported as a validation failure listing each field
 func ErrBadRequest(err error) render.Renderer {
-	return &ErrResponse{
-		Err:            err,
-		HTTPStatusCode: 400,
-		StatusText:     "Bad Request",
-		ErrorText:      err.Error(),
+	var v *ValidationError
+	if errors.As(err, &v) {
+		resp := newErrResponse(err, 422, "Validation Failed", CodeValidationFailed)
+		resp.Detail = "one or more fields are not valid"
+		resp.Errors = v.Fields
+		return resp
 	}
+	return newErrResponse(err, 400, "Bad Request", CodeBadRequest)
 }
 
-// ErrDB is and error resulting from a database query or process
+// ErrDB is an error returned by a store, its status comes from the kind of error
+// database messages are logged rather than sent so the schema isn't exposed
 func ErrDB(err error) render.Renderer {
-	e := err.(*mysql.MySQLError)
-	return &ErrResponse{
-		Err:            err,
-		HTTPStatusCode: 500,
-		StatusText:     "Database Error",
-		AppCode:        e.Number,
-		ErrorText:      e.Message,
+	kind := kindOf(err)
+	status, title := kind.Status()
+	var e *Error
+	if errors.As(err, &e) {
+		resp := newErrResponse(err, status, title, CodeInternal)
+		resp.Detail = e.Error()
+		return resp
+	}
+	resp := newErrResponse(err, status, title, CodeInternal)
+	switch kind {
+	case KindConflict:
+		resp.Code = CodeDuplicateEntry
+		resp.Detail = "a record with the same key already exists"
+	case KindValidation:
+		resp.Code = CodeInvalidReference
+		resp.Detail = "a referenced record does not exist or a value is not valid"
+	case KindNotFound:
+		resp.Code = CodeNotFound
+		resp.Detail = "the requested record does not exist"
+	case KindUnavailable:
+		resp.Code = CodeUnavailable
+		resp.Detail = "the database is unavailable, try again later"
+	default:
+		resp.Detail = "the request could not be completed"
 	}
+	resp.Type = problemType(resp.Code)
+	log.Printf("database error: %v", err)
+	return resp
 }
 
 // ErrRender is an error that occurs when a render method is called
 func ErrRender(err error) render.Renderer {
-	return &ErrResponse{
-		Err:            err,
-		HTTPStatusCode: 422,
-		StatusText:     "Error rendering response",
-		ErrorText:      err.Error(),
-	}
+	return newErrResponse(err, 422, "Error rendering response", CodeRenderFailed)
 }
 
 // ErrAccountLink is an error that occurs when trying to link summoner to account
 func ErrAccountLink(err error) render.Renderer {
-	return &ErrResponse{
-		Err:            err,
-		HTTPStatusCode: 400,
-		StatusText:     "Could Not Link Account",
-		ErrorText:      err.Error(),
+	return newErrResponse(err, 400, "Could Not Link Account", CodeAccountLinkFailed)
+}
+
+// ErrRiot is an error from the Riot API while linking a summoner
+// rate limits and outages are reported separately so clients know to retry later
+func ErrRiot(err error) render.Renderer {
+	switch {
+	case errors.Is(err, riot.ErrRateLimited):
+		log.Printf("riot error: %v", err)
+		resp := newErrResponse(err, 503, "Service Unavailable", CodeRiotRateLimited)
+		resp.Detail = "the riot api is rate limiting requests, try again later"
+		return resp
+	case errors.Is(err, riot.ErrUnavailable):
+		log.Printf("riot error: %v", err)
+		resp := newErrResponse(err, 502, "Bad Gateway", CodeRiotUnavailable)
+		resp.Detail = "the riot api is unavailable, try again later"
+		return resp
 	}
+	return ErrAccountLink(err)
 }
 
 // ErrUnauthorized is an error that occurs when a user is not authorized
 func ErrUnauthorized(err error) render.Renderer {
-	return &ErrResponse{
-		Err:            err,
-		HTTPStatusCode: 401,
-		StatusText:     "Unauthorized",
-		ErrorText:      err.Error(),
-	}
+	return newErrResponse(err, 401, "Unauthorized", CodeUnauthorized)
 }
 
 // ErrForbidden is an error that occurs when something is not allowed by the app
 func ErrForbidden(err error) render.Renderer {
-	return &ErrResponse{
-		Err:            err,
-		HTTPStatusCode: 403,
-		StatusText:     "Forbidden",
-		ErrorText:      err.Error(),
-	}
+	return newErrResponse(err, 403, "Forbidden", CodeForbidden)
+}
+
+// ErrNotFound is an error that occurs when a requested resource does not exist
+func ErrNotFound(err error) render.Renderer {
+	return newErrResponse(err, 404, "Not Found", CodeNotFound)
+}
+
+// ErrConflict is an error that occurs when a request conflicts with existing data
+func ErrConflict(err error) render.Renderer {
+	return newErrResponse(err, 409, "Conflict", CodeConflict)
+}
+
+// ErrGone is an error that occurs when a resource is no longer available
+func ErrGone(err error) render.Renderer {
+	return newErrResponse(err, 410, "Gone", CodeGone)
 }
@@ -0,0 +1,267 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/render"
+)
+
+// LinkedAccount is a verified game account of a user on one region
+// a user may link several accounts per region, one of which is primary and is
+// used wherever a single account of the region is needed
+// the profile and Ranks are refreshed from riot in the background, SyncedAt is 0 until the first sync
+type LinkedAccount struct {
+	ID            int64   `json:"id"`
+	UserID        int64   `json:"userId"`
+	PUUID         string  `json:"puuid"`
+	SummonerID    string  `json:"summonerId"`
+	Region        string  `json:"region"`
+	RiotID        string  `json:"riotId"`
+	Primary       bool    `json:"primary"`
+	VerifiedAt    int64   `json:"verifiedAt"`
+	CreatedAt     int64   `json:"createdAt"`
+	ProfileIconID int     `json:"profileIconId"`
+	SummonerLevel int64   `json:"summonerLevel"`
+	SyncedAt      int64   `json:"syncedAt"`
+	Ranks         []*Rank `json:"ranks,omitempty"`
+}
+
+var errLinkedAccountNotFound = newError(KindNotFound, CodeLinkedAccountNotFound, "linked account not found")
+
+// LinkedAccountResponse represents a linked account sent to the client
+type LinkedAccountResponse struct {
+	*LinkedAccount
+}
+
+// NewLinkedAccountResponse creates a response from a linked account
+func NewLinkedAccountResponse(account *LinkedAccount) *LinkedAccountResponse {
+	return &LinkedAccountResponse{LinkedAccount: account}
+}
+
+// Render allows for preprocessing of responses
+func (ar *LinkedAccountResponse) Render(w http.ResponseWriter, r *http.Request) error {
+	return nil
+}
+
+// NewLinkedAccountListResponse creates a list of linked account responses
+func NewLinkedAccountListResponse(accounts []*LinkedAccount) []render.Renderer {
+	list := []render.Renderer{}
+	for _, account := range accounts {
+		list = append(list, NewLinkedAccountResponse(account))
+	}
+	return list
+}
+
+const linkedAccountColumns = "id, userId, puuid, summonerId, region, riotId, isPrimary, verifiedAt, createdAt, profileIconId, summonerLevel, syncedAt"
+
+func scanLinkedAccount(row interface{ Scan(...interface{}) error }) (*LinkedAccount, error) {
+	var a LinkedAccount
+	err := row.Scan(&a.ID, &a.UserID, &a.PUUID, &a.SummonerID, &a.Region, &a.RiotID, &a.Primary, &a.VerifiedAt, &a.CreatedAt,
+		&a.ProfileIconID, &a.SummonerLevel, &a.SyncedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &a, nil
+}
+
+// insertLinkedAccount adds a linked account, making it primary if the user has none on its region
+// the user's row is locked before counting so two accounts linked at once can't both become primary,
+// the unique index on primary accounts has the last word
+func insertLinkedAccount(tx *sqlTx, a *LinkedAccount) error {
+	var userID int64
+	err := tx.QueryRow("SELECT id FROM account WHERE id=?{forupdate}", a.UserID).Scan(&userID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return errUserNotFound
+		}
+		return err
+	}
+	var primaries int
+	err = tx.QueryRow("SELECT COUNT(*) FROM linked_account WHERE userId=? AND region=? AND isPrimary=?", a.UserID, a.Region, true).Scan(&primaries)
+	if err != nil {
+		return err
+	}
+	a.Primary = primaries == 0
+	a.ID, err = tx.insertID("INSERT INTO linked_account(userId,puuid,summonerId,region,riotId,isPrimary,verifiedAt,createdAt) VALUES(?,?,?,?,?,?,?,?)",
+		a.UserID, a.PUUID, a.SummonerID, a.Region, a.RiotID, a.Primary, a.VerifiedAt, a.CreatedAt)
+	return err
+}
+
+// GetSummonerOwner returns the ID of the user puuid is linked to on any region or errUserNotFound
+func (m *SQLStore) GetSummonerOwner(puuid string) (int64, error) {
+	var userID int64
+	err := m.db.QueryRow("SELECT userId FROM linked_account WHERE puuid=? LIMIT 1", puuid).Scan(&userID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return 0, errUserNotFound
+		}
+		return 0, err
+	}
+	return userID, nil
+}
+
+// LinkAccount links the summoner of a verification to its user and removes the verification
+// linking an account that is already linked refreshes it and its verification time
+// a puuid can only be linked once, the owner is checked again here since the verification
+// was started and the unique index on puuid catches links made at the same time
+func (m *SQLStore) LinkAccount(v *SummonerVerification) (*LinkedAccount, error) {
+	now := time.Now().Unix()
+	var account *LinkedAccount
+	err := m.withTx("link account", func(tx *sqlTx) error {
+		row := tx.QueryRow("SELECT "+linkedAccountColumns+" FROM linked_account WHERE puuid=?", v.PUUID)
+		existing, err := scanLinkedAccount(row)
+		switch {
+		case err == sql.ErrNoRows:
+			account = &LinkedAccount{UserID: v.UserID, PUUID: v.PUUID, SummonerID: v.SummonerID, Region: v.Region, RiotID: v.RiotID, VerifiedAt: now, CreatedAt: now}
+			err = insertLinkedAccount(tx, account)
+		case err == nil && existing.UserID != v.UserID:
+			return errSummonerAlreadyLinked
+		case err == nil && existing.Region != v.Region:
+			return errSummonerOtherRegion
+		case err == nil:
+			account = existing
+			account.SummonerID, account.RiotID, account.VerifiedAt = v.SummonerID, v.RiotID, now
+			_, err = tx.Exec("UPDATE linked_account SET summonerId=?, riotId=?, verifiedAt=? WHERE id=?", account.SummonerID, account.RiotID, account.VerifiedAt, account.ID)
+		}
+		if err != nil {
+			return err
+		}
+		_, err = tx.Exec("DELETE FROM summoner_verification WHERE userId=?", v.UserID)
+		return err
+	})
+	if errors.Is(err, errSummonerOtherRegion) {
+		return nil, errSummonerOtherRegion
+	}
+	if kindOf(err) == KindConflict {
+		return nil, errSummonerAlreadyLinked
+	}
+	if err != nil {
+		return nil, err
+	}
+	return account, nil
+}
+
+// GetLinkedAccounts returns the accounts linked to userID by region, primary accounts first,
+// along with their current ranks
+func (m *SQLStore) GetLinkedAccounts(userID int64) ([]*LinkedAccount, error) {
+	var accounts []*LinkedAccount
+	rows, err := m.db.Query("SELECT "+linkedAccountColumns+" FROM linked_account WHERE userId=? ORDER BY region, isPrimary DESC, id", userID)
+	if err != nil {
+		return accounts, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		account, err := scanLinkedAccount(rows)
+		if err != nil {
+			return accounts, err
+		}
+		accounts = append(accounts, account)
+	}
+	err = rows.Err()
+	if err != nil {
+		return accounts, err
+	}
+	ranks, err := m.currentRanks("a.userId = ?", userID)
+	if err != nil {
+		return accounts, err
+	}
+	attachRanks(accounts, ranks)
+	return accounts, nil
+}
+
+// attachRanks sets the Ranks of each account from snapshots
+func attachRanks(accounts []*LinkedAccount, snapshots []*RankSnapshot) {
+	for _, account := range accounts {
+		for _, s := range snapshots {
+			if s.AccountID == account.ID {
+				rank := s.Rank
+				account.Ranks = append(account.Ranks, &rank)
+			}
+		}
+	}
+}
+
+// SetPrimaryAccount makes accountID the primary account of userID on its region
+func (m *SQLStore) SetPrimaryAccount(userID, accountID int64) error {
+	return m.withTx("set primary account", func(tx *sqlTx) error {
+		var region string
+		err := tx.QueryRow("SELECT region FROM linked_account WHERE id=? AND userId=?", accountID, userID).Scan(&region)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				return errLinkedAccountNotFound
+			}
+			return err
+		}
+		_, err = tx.Exec("UPDATE linked_account SET isPrimary=? WHERE userId=? AND region=?", false, userID, region)
+		if err != nil {
+			return err
+		}
+		_, err = tx.Exec("UPDATE linked_account SET isPrimary=? WHERE id=?", true, accountID)
+		return err
+	})
+}
+
+// UnlinkAccount removes a linked account of userID and its rank history, the oldest
+// remaining account on its region becomes primary if it was
+func (m *SQLStore) UnlinkAccount(userID, accountID int64) error {
+	return m.withTx("unlink account", func(tx *sqlTx) error {
+		row := tx.QueryRow("SELECT "+linkedAccountColumns+" FROM linked_account WHERE id=? AND userId=?", accountID, userID)
+		account, err := scanLinkedAccount(row)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				return errLinkedAccountNotFound
+			}
+			return err
+		}
+		_, err = tx.Exec("DELETE FROM rank_snapshot WHERE accountId=?", accountID)
+		if err != nil {
+			return err
+		}
+		_, err = tx.Exec("DELETE FROM linked_account WHERE id=?", accountID)
+		if err != nil {
+			return err
+		}
+		if !account.Primary {
+			return nil
+		}
+		var next int64
+		err = tx.QueryRow("SELECT id FROM linked_account WHERE userId=? AND region=? ORDER BY id LIMIT 1", userID, account.Region).Scan(&next)
+		if err == sql.ErrNoRows {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		_, err = tx.Exec("UPDATE linked_account SET isPrimary=? WHERE id=?", true, next)
+		return err
+	})
+}
+
+// UnlinkAllAccounts removes every account, their rank history and any pending verification of userID
+func (m *SQLStore) UnlinkAllAccounts(userID int64) error {
+	return m.withTx("unlink accounts", func(tx *sqlTx) error {
+		_, err := tx.Exec("DELETE FROM summoner_verification WHERE userId=?", userID)
+		if err != nil {
+			return err
+		}
+		_, err = tx.Exec("DELETE FROM rank_snapshot WHERE accountId IN (SELECT id FROM linked_account WHERE userId=?)", userID)
+		if err != nil {
+			return err
+		}
+		res, err := tx.Exec("DELETE FROM linked_account WHERE userId=?", userID)
+		if err != nil {
+			return err
+		}
+		n, err := res.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if n == 0 {
+			return errSummonerNotLinked
+		}
+		return nil
+	})
+}
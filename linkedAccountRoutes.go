@@ -0,0 +1,72 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi"
+	"github.com/go-chi/render"
+)
+
+// accountIDParam parses the accountID url parameter
+func accountIDParam(r *http.Request) (int64, error) {
+	accountID, err := strconv.ParseInt(chi.URLParam(r, "accountID"), 10, 64)
+	if err != nil {
+		return 0, errors.New("account id not valid")
+	}
+	return accountID, nil
+}
+
+// GetMyAccounts renders the accounts linked to the authenticated user
+func (s *Server) GetMyAccounts(w http.ResponseWriter, r *http.Request) {
+	s.renderLinkedAccounts(w, r, protectedID(r))
+}
+
+// GetUserAccounts renders the accounts linked to a user, which must be the requesting user
+func (s *Server) GetUserAccounts(w http.ResponseWriter, r *http.Request) {
+	if !requireSelf(w, r) {
+		return
+	}
+	s.renderLinkedAccounts(w, r, protectedID(r))
+}
+
+func (s *Server) renderLinkedAccounts(w http.ResponseWriter, r *http.Request, userID int64) {
+	accounts, err := s.users.GetLinkedAccounts(userID)
+	if err != nil {
+		render.Render(w, r, ErrDB(err))
+		return
+	}
+	if err := render.RenderList(w, r, NewLinkedAccountListResponse(accounts)); err != nil {
+		render.Render(w, r, ErrRender(err))
+		return
+	}
+}
+
+// SetPrimaryAccount makes a linked account the one used for its region
+func (s *Server) SetPrimaryAccount(w http.ResponseWriter, r *http.Request) {
+	accountID, err := accountIDParam(r)
+	if err != nil {
+		render.Render(w, r, ErrBadRequest(err))
+		return
+	}
+	if err := s.users.SetPrimaryAccount(protectedID(r), accountID); err != nil {
+		render.Render(w, r, ErrDB(err))
+		return
+	}
+	render.NoContent(w, r)
+}
+
+// UnlinkAccount removes one linked account of the authenticated user
+func (s *Server) UnlinkAccount(w http.ResponseWriter, r *http.Request) {
+	accountID, err := accountIDParam(r)
+	if err != nil {
+		render.Render(w, r, ErrBadRequest(err))
+		return
+	}
+	if err := s.users.UnlinkAccount(protectedID(r), accountID); err != nil {
+		render.Render(w, r, ErrDB(err))
+		return
+	}
+	render.NoContent(w, r)
+}
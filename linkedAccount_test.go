@@ -0,0 +1,178 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/anthonyrouseau/lss-api/riot"
+)
+
+// linkTestAccount links the account puuid on region to userID as if its verification had passed
+func linkTestAccount(t *testing.T, store Store, userID int64, puuid, region string) *LinkedAccount {
+	t.Helper()
+	account, err := store.LinkAccount(&SummonerVerification{UserID: userID, RiotID: puuid + "#" + region, PUUID: puuid, SummonerID: "summoner-" + puuid, Region: region})
+	if err != nil {
+		t.Fatalf("link %s: %v", puuid, err)
+	}
+	return account
+}
+
+// primaryOf returns the ids of the primary accounts of userID by region
+func primaryOf(t *testing.T, store Store, userID int64) map[string]int64 {
+	t.Helper()
+	accounts, err := store.GetLinkedAccounts(userID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	primary := map[string]int64{}
+	for _, a := range accounts {
+		if a.Primary {
+			if _, ok := primary[a.Region]; ok {
+				t.Fatalf("two primary accounts on %s: %+v", a.Region, accounts)
+			}
+			primary[a.Region] = a.ID
+		}
+	}
+	return primary
+}
+
+func TestLinkAccount(t *testing.T) {
+	forEachStore(t, func(t *testing.T, store Store) {
+		user := newTestUser(t, store, "player")
+		other := newTestUser(t, store, "other")
+
+		main := linkTestAccount(t, store, user, "main", "na1")
+		smurf := linkTestAccount(t, store, user, "smurf", "na1")
+		euw := linkTestAccount(t, store, user, "euw", "euw1")
+		if !main.Primary || smurf.Primary || !euw.Primary {
+			t.Errorf("primary flags = %v %v %v, want the first account of each region", main.Primary, smurf.Primary, euw.Primary)
+		}
+
+		relinked, err := store.LinkAccount(&SummonerVerification{UserID: user, RiotID: "Renamed#NA1", PUUID: "main", SummonerID: "summoner-main", Region: "na1"})
+		if err != nil || relinked.ID != main.ID || relinked.RiotID != "Renamed#NA1" || !relinked.Primary {
+			t.Errorf("relink = %+v, %v", relinked, err)
+		}
+		if owner, err := store.GetSummonerOwner("main"); err != nil || owner != user {
+			t.Errorf("owner = %d, %v, want %d", owner, err, user)
+		}
+
+		tests := []struct {
+			name string
+			v    *SummonerVerification
+			err  error
+		}{
+			{"by another user", &SummonerVerification{UserID: other, PUUID: "main", Region: "na1"}, errSummonerAlreadyLinked},
+			{"on another region", &SummonerVerification{UserID: user, PUUID: "main", Region: "euw1"}, errSummonerOtherRegion},
+			{"by an unknown user", &SummonerVerification{UserID: 999, PUUID: "new", Region: "na1"}, errUserNotFound},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				if _, err := store.LinkAccount(tt.v); !errors.Is(err, tt.err) {
+					t.Errorf("link: %v, want %v", err, tt.err)
+				}
+			})
+		}
+
+		accounts, err := store.GetLinkedAccounts(user)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var order []int64
+		for _, a := range accounts {
+			order = append(order, a.ID)
+		}
+		if want := []int64{euw.ID, main.ID, smurf.ID}; fmt.Sprint(order) != fmt.Sprint(want) {
+			t.Errorf("accounts = %v, want %v by region with primary accounts first", order, want)
+		}
+	})
+}
+
+func TestSetPrimaryAccount(t *testing.T) {
+	forEachStore(t, func(t *testing.T, store Store) {
+		user := newTestUser(t, store, "player")
+		other := newTestUser(t, store, "other")
+		main := linkTestAccount(t, store, user, "main", "na1")
+		smurf := linkTestAccount(t, store, user, "smurf", "na1")
+		euw := linkTestAccount(t, store, user, "euw", "euw1")
+
+		if err := store.SetPrimaryAccount(user, smurf.ID); err != nil {
+			t.Fatal(err)
+		}
+		if got := primaryOf(t, store, user); got["na1"] != smurf.ID || got["euw1"] != euw.ID {
+			t.Errorf("primary accounts = %v, want na1 %d and euw1 %d", got, smurf.ID, euw.ID)
+		}
+		if err := store.SetPrimaryAccount(other, main.ID); !errors.Is(err, errLinkedAccountNotFound) {
+			t.Errorf("set primary account of another user: %v, want %v", err, errLinkedAccountNotFound)
+		}
+		if got := primaryOf(t, store, user); got["na1"] != smurf.ID {
+			t.Errorf("primary account after a refused change = %v", got)
+		}
+	})
+}
+
+func TestUnlinkAccount(t *testing.T) {
+	forEachStore(t, func(t *testing.T, store Store) {
+		user := newTestUser(t, store, "player")
+		other := newTestUser(t, store, "other")
+		main := linkTestAccount(t, store, user, "main", "na1")
+		smurf := linkTestAccount(t, store, user, "smurf", "na1")
+		third := linkTestAccount(t, store, user, "third", "na1")
+		gold := &AccountSync{AccountID: main.ID, SummonerID: "summoner-main", Ranks: []*Rank{{Queue: riot.QueueSolo, Tier: "GOLD", Division: "II"}}, SyncedAt: time.Now().Unix()}
+		if err := store.SaveAccountSync(gold); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := store.UnlinkAccount(other, main.ID); !errors.Is(err, errLinkedAccountNotFound) {
+			t.Errorf("unlink account of another user: %v, want %v", err, errLinkedAccountNotFound)
+		}
+		if err := store.UnlinkAccount(user, smurf.ID); err != nil {
+			t.Fatal(err)
+		}
+		if got := primaryOf(t, store, user); got["na1"] != main.ID {
+			t.Errorf("unlinking another account changed the primary: %v", got)
+		}
+		if err := store.UnlinkAccount(user, main.ID); err != nil {
+			t.Fatal(err)
+		}
+		if got := primaryOf(t, store, user); got["na1"] != third.ID {
+			t.Errorf("primary after unlinking it = %v, want the oldest remaining %d", got, third.ID)
+		}
+		if history, err := store.GetRankHistory(user, "", "", 10); err != nil || len(history) != 0 {
+			t.Errorf("rank history of unlinked accounts = %+v, %v", history, err)
+		}
+		if err := store.UnlinkAccount(user, main.ID); !errors.Is(err, errLinkedAccountNotFound) {
+			t.Errorf("unlink twice: %v, want %v", err, errLinkedAccountNotFound)
+		}
+		// the puuid is free once unlinked
+		linkTestAccount(t, store, other, "main", "na1")
+
+		if err := store.UnlinkAllAccounts(user); err != nil {
+			t.Fatal(err)
+		}
+		if err := store.UnlinkAllAccounts(user); !errors.Is(err, errSummonerNotLinked) {
+			t.Errorf("unlink all twice: %v, want %v", err, errSummonerNotLinked)
+		}
+	})
+}
+
+func TestPrimaryLinkedAccountIndex(t *testing.T) {
+	// the count in insertLinkedAccount is guarded by a lock, the index has the last word
+	store := newTestSQLStore(t)
+	user := newTestUser(t, store, "player")
+	insert := "INSERT INTO linked_account(userId,puuid,summonerId,region,isPrimary,verifiedAt,createdAt) VALUES(?,?,?,?,?,?,?)"
+	now := time.Now().Unix()
+	if _, err := store.db.Exec(insert, user, "main", "s1", "na1", true, now, now); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.db.Exec(insert, user, "euw", "s2", "euw1", true, now, now); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.db.Exec(insert, user, "smurf", "s3", "na1", false, now, now); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.db.Exec(insert, user, "second", "s4", "na1", true, now, now); kindOf(err) != KindConflict {
+		t.Errorf("second primary account: %v, want a conflict", err)
+	}
+}
@@ -0,0 +1,1161 @@
+package main
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// errors returned by MemoryStore where a database would report a constraint violation
+var (
+	errDuplicateEntry = &DBError{Kind: KindConflict, Code: "duplicate", Message: "duplicate entry"}
+	errForeignKey     = &DBError{Kind: KindValidation, Code: "foreign key", Message: "foreign key constraint fails"}
+)
+
+// searchPageSize matches the LIMIT used by the SQL search queries
+const searchPageSize = 10
+
+var (
+	_ UserStore   = (*MemoryStore)(nil)
+	_ TeamStore   = (*MemoryStore)(nil)
+	_ InviteStore = (*MemoryStore)(nil)
+)
+
+type memSession struct {
+	Session
+	tokenHash string
+	revoked   bool
+}
+
+type memRosterEntry struct {
+	role     string
+	joinedAt int64
+}
+
+type memRosterRemoval struct {
+	teamID, userID, removedBy int64
+	reason                    string
+	removedAt                 int64
+}
+
+type memJoinLink struct {
+	TeamJoinLink
+	createdBy int64
+	revoked   bool
+}
+
+// MemoryStore implements UserStore, TeamStore and InviteStore in memory
+// it enforces the same uniqueness and foreign key rules as the SQL schema
+// so it can stand in for a database in development and tests
+type MemoryStore struct {
+	mu             sync.Mutex
+	lastID         map[string]int64
+	users          map[int64]*User
+	sessions       map[string]*memSession
+	teams          map[int64]*Team
+	roster         map[int64]map[int64]*memRosterEntry
+	rosterRemovals []memRosterRemoval
+	invites        map[int64]*TeamInvite
+	joinLinks      map[string]*memJoinLink
+	joinRequests   map[int64]*TeamJoinRequest
+	verifications  map[int64]*SummonerVerification
+	accounts       map[int64]*LinkedAccount
+	rankSnapshots  []*RankSnapshot
+}
+
+// NewMemoryStore creates an empty MemoryStore
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		lastID:        map[string]int64{},
+		users:         map[int64]*User{},
+		sessions:      map[string]*memSession{},
+		teams:         map[int64]*Team{},
+		roster:        map[int64]map[int64]*memRosterEntry{},
+		invites:       map[int64]*TeamInvite{},
+		joinLinks:     map[string]*memJoinLink{},
+		joinRequests:  map[int64]*TeamJoinRequest{},
+		verifications: map[int64]*SummonerVerification{},
+		accounts:      map[int64]*LinkedAccount{},
+	}
+}
+
+// nextID returns the next auto increment value for table
+func (m *MemoryStore) nextID(table string) int64 {
+	m.lastID[table]++
+	return m.lastID[table]
+}
+
+// pageBounds returns the slice bounds of a LIMIT/OFFSET page over n rows
+func pageBounds(n, offset, limit int) (int, int) {
+	if offset > n {
+		offset = n
+	}
+	end := offset + limit
+	if end > n {
+		end = n
+	}
+	return offset, end
+}
+
+// hasPrefixFold matches like a LIKE 'prefix%' query on a case insensitive column
+func hasPrefixFold(s, prefix string) bool {
+	return len(s) >= len(prefix) && strings.EqualFold(s[:len(prefix)], prefix)
+}
+
+// NewUser creates a user, user.Password must already be hashed
+func (m *MemoryStore) NewUser(user *User) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, u := range m.users {
+		if strings.EqualFold(u.Username, user.Username) {
+			return 0, errDuplicateEntry
+		}
+	}
+	for _, account := range user.Accounts {
+		if m.accountTaken(account.PUUID) {
+			return 0, &TxError{Op: "new user", Err: errDuplicateEntry}
+		}
+	}
+	stored := *user
+	stored.ID = m.nextID("account")
+	stored.Accounts = nil
+	m.users[stored.ID] = &stored
+	for _, account := range user.Accounts {
+		account.UserID = stored.ID
+		m.insertAccount(account)
+	}
+	return stored.ID, nil
+}
+
+// SearchUsername returns a page of users with usernames starting with searchValue
+func (m *MemoryStore) SearchUsername(searchValue string, offset int) ([]*User, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var users []*User
+	for _, u := range m.users {
+		if hasPrefixFold(u.Username, searchValue) {
+			users = append(users, &User{ID: u.ID, Username: u.Username})
+		}
+	}
+	sort.Slice(users, func(i, j int) bool { return users[i].ID < users[j].ID })
+	from, to := pageBounds(len(users), offset, searchPageSize)
+	return users[from:to], nil
+}
+
+// GetCredentials returns the ID and stored password of username or errUserNotFound
+func (m *MemoryStore) GetCredentials(username string) (int64, string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, u := range m.users {
+		if strings.EqualFold(u.Username, username) {
+			return u.ID, u.Password, nil
+		}
+	}
+	return 0, "", errUserNotFound
+}
+
+// SetSummonerVerification replaces the pending verification of v.UserID
+func (m *MemoryStore) SetSummonerVerification(v *SummonerVerification) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.users[v.UserID]; !ok {
+		return &TxError{Op: "set summoner verification", Err: errForeignKey}
+	}
+	stored := *v
+	m.verifications[v.UserID] = &stored
+	return nil
+}
+
+// GetSummonerVerification returns the pending verification of userID or errVerificationNotFound
+func (m *MemoryStore) GetSummonerVerification(userID int64) (*SummonerVerification, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	v, ok := m.verifications[userID]
+	if !ok {
+		return nil, errVerificationNotFound
+	}
+	copied := *v
+	return &copied, nil
+}
+
+// SetPassword replaces the stored password hash of a user
+func (m *MemoryStore) SetPassword(userID int64, hash string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if u, ok := m.users[userID]; ok {
+		u.Password = hash
+	}
+	return nil
+}
+
+// NewSession starts a session for userID and returns it with its first refresh token
+func (m *MemoryStore) NewSession(userID int64, userAgent string) (*Session, string, error) {
+	id, err := randomToken(16)
+	if err != nil {
+		return nil, "", err
+	}
+	secret, err := randomToken(32)
+	if err != nil {
+		return nil, "", err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.users[userID]; !ok {
+		return nil, "", errForeignKey
+	}
+	now := time.Now()
+	session := Session{
+		ID:         id,
+		UserID:     userID,
+		UserAgent:  userAgent,
+		CreatedAt:  now.Unix(),
+		LastUsedAt: now.Unix(),
+		ExpiresAt:  now.Add(refreshTTL).Unix(),
+	}
+	m.sessions[id] = &memSession{Session: session, tokenHash: hashToken(secret)}
+	return &session, id + "." + secret, nil
+}
+
+// RotateSession exchanges a refresh token for a new one in the same session
+// presenting a token that was already rotated out revokes the session
+func (m *MemoryStore) RotateSession(refreshToken string) (*Session, string, error) {
+	id, secret, err := splitRefreshToken(refreshToken)
+	if err != nil {
+		return nil, "", err
+	}
+	newSecret, err := randomToken(32)
+	if err != nil {
+		return nil, "", err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	now := time.Now()
+	s, ok := m.sessions[id]
+	if !ok || s.revoked || s.ExpiresAt <= now.Unix() {
+		return nil, "", errSessionInvalid
+	}
+	if s.tokenHash != hashToken(secret) {
+		s.revoked = true
+		return nil, "", errSessionReused
+	}
+	s.tokenHash = hashToken(newSecret)
+	s.LastUsedAt = now.Unix()
+	s.ExpiresAt = now.Add(refreshTTL).Unix()
+	session := s.Session
+	return &session, id + "." + newSecret, nil
+}
+
+// SessionActive reports whether a session exists for userID and has not been revoked or expired
+func (m *MemoryStore) SessionActive(sessionID string, userID int64) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	s, ok := m.sessions[sessionID]
+	return ok && s.UserID == userID && !s.revoked && s.ExpiresAt > time.Now().Unix(), nil
+}
+
+// RevokeSession revokes one session of userID or returns errSessionMissing
+func (m *MemoryStore) RevokeSession(sessionID string, userID int64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	s, ok := m.sessions[sessionID]
+	if !ok || s.UserID != userID || s.revoked {
+		return errSessionMissing
+	}
+	s.revoked = true
+	return nil
+}
+
+// RevokeOtherSessions revokes every active session of userID except keepID
+func (m *MemoryStore) RevokeOtherSessions(userID int64, keepID string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for id, s := range m.sessions {
+		if s.UserID == userID && id != keepID {
+			s.revoked = true
+		}
+	}
+	return nil
+}
+
+// GetUserSessions returns the active sessions of userID, most recently used first
+func (m *MemoryStore) GetUserSessions(userID int64) ([]*Session, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var sessions []*Session
+	now := time.Now().Unix()
+	for _, s := range m.sessions {
+		if s.UserID == userID && !s.revoked && s.ExpiresAt > now {
+			session := s.Session
+			sessions = append(sessions, &session)
+		}
+	}
+	sort.Slice(sessions, func(i, j int) bool { return sessions[i].LastUsedAt > sessions[j].LastUsedAt })
+	return sessions, nil
+}
+
+// CreateTeam creates a team and adds its captain to the roster
+func (m *MemoryStore) CreateTeam(team *Team) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.users[team.Captain]; !ok {
+		return &TxError{Op: "create team", Err: errForeignKey}
+	}
+	stored := *team
+	stored.ID = m.nextID("team")
+	stored.DisbandedAt = 0
+	m.teams[stored.ID] = &stored
+	m.roster[stored.ID] = map[int64]*memRosterEntry{
+		team.Captain: {role: roleCaptain, joinedAt: time.Now().Unix()},
+	}
+	team.ID = stored.ID
+	return nil
+}
+
+// GetTeam returns a team by ID, including disbanded teams, or errTeamNotFound
+func (m *MemoryStore) GetTeam(teamID int64) (*Team, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	t, ok := m.teams[teamID]
+	if !ok {
+		return nil, errTeamNotFound
+	}
+	team := *t
+	return &team, nil
+}
+
+// SearchTeamName returns a page of teams with names starting with searchValue
+func (m *MemoryStore) SearchTeamName(searchValue string, offset int) ([]*Team, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var teams []*Team
+	for _, t := range m.teams {
+		if t.DisbandedAt == 0 && hasPrefixFold(t.Name, searchValue) {
+			team := *t
+			teams = append(teams, &team)
+		}
+	}
+	sort.Slice(teams, func(i, j int) bool { return teams[i].ID < teams[j].ID })
+	from, to := pageBounds(len(teams), offset, searchPageSize)
+	return teams[from:to], nil
+}
+
+// GetUserTeams returns all teams of which userID is a member
+func (m *MemoryStore) GetUserTeams(userID string) ([]*Team, error) {
+	id, err := strconv.ParseInt(userID, 10, 64)
+	if err != nil {
+		return nil, nil
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var teams []*Team
+	for teamID, members := range m.roster {
+		if _, ok := members[id]; ok {
+			team := *m.teams[teamID]
+			teams = append(teams, &team)
+		}
+	}
+	sort.Slice(teams, func(i, j int) bool { return teams[i].ID < teams[j].ID })
+	return teams, nil
+}
+
+// SetTeamAcceptsRequests sets whether a team accepts join requests
+func (m *MemoryStore) SetTeamAcceptsRequests(teamID int64, accepts bool) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if t, ok := m.teams[teamID]; ok {
+		t.AcceptsRequests = accepts
+	}
+	return nil
+}
+
+// DisbandTeam clears the roster, invites, join links and join requests of a team
+// the team is kept as a tombstone so past results can still show its name
+func (m *MemoryStore) DisbandTeam(teamID int64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	t, ok := m.teams[teamID]
+	if !ok || t.DisbandedAt != 0 {
+		return &TxError{Op: "disband team", Err: errTeamNotFound}
+	}
+	t.DisbandedAt = time.Now().Unix()
+	t.AcceptsRequests = false
+	delete(m.roster, teamID)
+	for id, invite := range m.invites {
+		if invite.Team == teamID {
+			delete(m.invites, id)
+		}
+	}
+	for code, link := range m.joinLinks {
+		if link.Team == teamID {
+			delete(m.joinLinks, code)
+		}
+	}
+	for id, req := range m.joinRequests {
+		if req.Team == teamID {
+			delete(m.joinRequests, id)
+		}
+	}
+	return nil
+}
+
+// IsCaptain reports whether userID is the captain of teamID
+func (m *MemoryStore) IsCaptain(userID, teamID int64) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	t, ok := m.teams[teamID]
+	return ok && t.DisbandedAt == 0 && t.Captain == userID, nil
+}
+
+// addToRoster adds userID to teamID with role, the caller must hold m.mu
+func (m *MemoryStore) addToRoster(userID, teamID int64, role string) error {
+	if _, ok := m.teams[teamID]; !ok {
+		return errForeignKey
+	}
+	if _, ok := m.users[userID]; !ok {
+		return errForeignKey
+	}
+	members, ok := m.roster[teamID]
+	if !ok {
+		members = map[int64]*memRosterEntry{}
+		m.roster[teamID] = members
+	}
+	if _, ok := members[userID]; ok {
+		return errDuplicateEntry
+	}
+	members[userID] = &memRosterEntry{role: role, joinedAt: time.Now().Unix()}
+	return nil
+}
+
+// EditRoster removes userID from the roster of teamID
+func (m *MemoryStore) EditRoster(action string, userID, teamID int64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	switch action {
+	case "remove":
+		entry, ok := m.roster[teamID][userID]
+		if !ok {
+			return errNotOnRoster
+		}
+		if entry.role == roleCaptain {
+			return errCaptainCannotLeave
+		}
+		delete(m.roster[teamID], userID)
+		return nil
+	default:
+		return errActionNotValid
+	}
+}
+
+// GetRosterRole returns the role of userID on teamID or errNotOnRoster
+func (m *MemoryStore) GetRosterRole(userID, teamID int64) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	entry, ok := m.roster[teamID][userID]
+	if !ok {
+		return "", errNotOnRoster
+	}
+	return entry.role, nil
+}
+
+// SetRosterRole changes the role of a roster member other than the captain
+func (m *MemoryStore) SetRosterRole(userID, teamID int64, role string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	entry, ok := m.roster[teamID][userID]
+	if !ok {
+		return errNotOnRoster
+	}
+	if entry.role != roleCaptain {
+		entry.role = role
+	}
+	return nil
+}
+
+// TransferCaptain makes newCaptain the captain of teamID and the old captain a co-captain
+func (m *MemoryStore) TransferCaptain(teamID, oldCaptain, newCaptain int64) error {
+	if oldCaptain == newCaptain {
+		return errSameCaptain
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	newEntry, ok := m.roster[teamID][newCaptain]
+	if !ok {
+		return &TxError{Op: "transfer captain", Err: errNotOnRoster}
+	}
+	t, ok := m.teams[teamID]
+	if !ok || t.Captain != oldCaptain {
+		return &TxError{Op: "transfer captain", Err: errNotCaptain}
+	}
+	t.Captain = newCaptain
+	newEntry.role = roleCaptain
+	if oldEntry, ok := m.roster[teamID][oldCaptain]; ok {
+		oldEntry.role = roleCoCaptain
+	}
+	return nil
+}
+
+// KickFromRoster removes userID from teamID and records who removed them and why
+func (m *MemoryStore) KickFromRoster(userID, teamID, removedBy int64, reason string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	entry, ok := m.roster[teamID][userID]
+	if !ok || entry.role == roleCaptain {
+		return &TxError{Op: "kick from roster", Err: errNotOnRoster}
+	}
+	delete(m.roster[teamID], userID)
+	m.rosterRemovals = append(m.rosterRemovals, memRosterRemoval{
+		teamID:    teamID,
+		userID:    userID,
+		removedBy: removedBy,
+		reason:    reason,
+		removedAt: time.Now().Unix(),
+	})
+	return nil
+}
+
+// GetTeamRoster returns a page of the roster of teamID, captain and co-captains first
+func (m *MemoryStore) GetTeamRoster(teamID int64, limit, offset int) ([]*RosterMember, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var members []*RosterMember
+	var region string
+	if t, ok := m.teams[teamID]; ok {
+		region = t.Region
+	}
+	for userID, entry := range m.roster[teamID] {
+		u := m.users[userID]
+		member := &RosterMember{
+			UserID:   userID,
+			Username: u.Username,
+			Role:     entry.role,
+			JoinedAt: entry.joinedAt,
+		}
+		if account := m.primaryAccount(userID, region); account != nil {
+			member.SummonerID = account.SummonerID
+		}
+		members = append(members, member)
+	}
+	rank := func(role string) int {
+		switch role {
+		case roleCaptain:
+			return 0
+		case roleCoCaptain:
+			return 1
+		}
+		return 2
+	}
+	sort.Slice(members, func(i, j int) bool {
+		a, b := members[i], members[j]
+		if rank(a.Role) != rank(b.Role) {
+			return rank(a.Role) < rank(b.Role)
+		}
+		if a.JoinedAt != b.JoinedAt {
+			return a.JoinedAt < b.JoinedAt
+		}
+		return a.UserID < b.UserID
+	})
+	from, to := pageBounds(len(members), offset, limit)
+	return members[from:to], nil
+}
+
+// inviteWithName copies an invite and fills in its team name and status, the caller must hold m.mu
+func (m *MemoryStore) inviteWithName(stored *TeamInvite) *TeamInvite {
+	invite := *stored
+	if t, ok := m.teams[invite.Team]; ok {
+		invite.Name = t.Name
+	}
+	invite.effectiveStatus()
+	return &invite
+}
+
+// NewTeamInvite creates a pending invite or returns errInviteExists if one is already live
+// an expired invite to the same invitee is replaced
+func (m *MemoryStore) NewTeamInvite(invite *TeamInvite) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	now := time.Now()
+	for _, existing := range m.invites {
+		if existing.Team == invite.Team && existing.Invitee == invite.Invitee &&
+			existing.Status == inviteStatusPending && existing.ExpiresAt > now.Unix() {
+			return &TxError{Op: "create invite", Err: errInviteExists}
+		}
+	}
+	if _, ok := m.teams[invite.Team]; !ok {
+		return &TxError{Op: "create invite", Err: errForeignKey}
+	}
+	if _, ok := m.users[invite.Invitee]; !ok {
+		return &TxError{Op: "create invite", Err: errForeignKey}
+	}
+	for id, existing := range m.invites {
+		if existing.Team == invite.Team && existing.Invitee == invite.Invitee {
+			delete(m.invites, id)
+		}
+	}
+	invite.ID = m.nextID("team_invite")
+	invite.Status = inviteStatusPending
+	invite.CreatedAt = now.Unix()
+	invite.ExpiresAt = now.Add(inviteTTL).Unix()
+	stored := *invite
+	stored.Name = ""
+	m.invites[stored.ID] = &stored
+	return nil
+}
+
+// GetTeamInvite returns an invite by ID or errInviteNotFound
+func (m *MemoryStore) GetTeamInvite(inviteID int64) (*TeamInvite, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	stored, ok := m.invites[inviteID]
+	if !ok {
+		return nil, errInviteNotFound
+	}
+	return m.inviteWithName(stored), nil
+}
+
+// GetUserTeamInvites returns the live invites sent to userID
+func (m *MemoryStore) GetUserTeamInvites(userID string) ([]*TeamInvite, error) {
+	id, err := strconv.ParseInt(userID, 10, 64)
+	if err != nil {
+		return nil, nil
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var invites []*TeamInvite
+	now := time.Now().Unix()
+	for _, stored := range m.invites {
+		if stored.Invitee == id && stored.Status == inviteStatusPending && stored.ExpiresAt > now {
+			invites = append(invites, m.inviteWithName(stored))
+		}
+	}
+	sort.Slice(invites, func(i, j int) bool { return invites[i].ID < invites[j].ID })
+	return invites, nil
+}
+
+// GetTeamInvites returns every invite sent by a team, newest first
+func (m *MemoryStore) GetTeamInvites(teamID int64) ([]*TeamInvite, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var invites []*TeamInvite
+	for _, stored := range m.invites {
+		if stored.Team == teamID {
+			invites = append(invites, m.inviteWithName(stored))
+		}
+	}
+	sort.Slice(invites, func(i, j int) bool {
+		if invites[i].CreatedAt != invites[j].CreatedAt {
+			return invites[i].CreatedAt > invites[j].CreatedAt
+		}
+		return invites[i].ID > invites[j].ID
+	})
+	return invites, nil
+}
+
+// AcceptTeamInvite adds the invitee to the roster and deletes the invite
+func (m *MemoryStore) AcceptTeamInvite(invite *TeamInvite) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.invites[invite.ID]; !ok {
+		return &TxError{Op: "accept invite", Err: errInviteNotFound}
+	}
+	if err := m.addToRoster(invite.Invitee, invite.Team, roleMember); err != nil {
+		return &TxError{Op: "accept invite", Err: err}
+	}
+	delete(m.invites, invite.ID)
+	return nil
+}
+
+// DeleteTeamInvite deletes an invite or returns errInviteNotFound
+func (m *MemoryStore) DeleteTeamInvite(inviteID int64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.invites[inviteID]; !ok {
+		return errInviteNotFound
+	}
+	delete(m.invites, inviteID)
+	return nil
+}
+
+// ExpireTeamInvites marks pending invites past their expiry as expired
+// and deletes expired invites older than inviteRetention
+func (m *MemoryStore) ExpireTeamInvites(now time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	cutoff := now.Add(-inviteRetention).Unix()
+	for id, invite := range m.invites {
+		if invite.Status == inviteStatusPending && invite.ExpiresAt <= now.Unix() {
+			invite.Status = inviteStatusExpired
+		}
+		if invite.Status == inviteStatusExpired && invite.ExpiresAt <= cutoff {
+			delete(m.invites, id)
+		}
+	}
+	return nil
+}
+
+// NewTeamJoinLink creates a join link with a random code
+func (m *MemoryStore) NewTeamJoinLink(link *TeamJoinLink, createdBy int64) error {
+	code, err := randomToken(16)
+	if err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.teams[link.Team]; !ok {
+		return errForeignKey
+	}
+	if _, ok := m.joinLinks[code]; ok {
+		return errDuplicateEntry
+	}
+	link.Code = code
+	link.CreatedAt = time.Now().Unix()
+	m.joinLinks[code] = &memJoinLink{TeamJoinLink: *link, createdBy: createdBy}
+	return nil
+}
+
+// GetTeamJoinLinks returns the join links of a team that have not been revoked
+func (m *MemoryStore) GetTeamJoinLinks(teamID int64) ([]*TeamJoinLink, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var links []*TeamJoinLink
+	for _, stored := range m.joinLinks {
+		if stored.Team == teamID && !stored.revoked {
+			link := stored.TeamJoinLink
+			links = append(links, &link)
+		}
+	}
+	sort.Slice(links, func(i, j int) bool { return links[i].CreatedAt > links[j].CreatedAt })
+	return links, nil
+}
+
+// RevokeTeamJoinLink stops a join link from being redeemed
+func (m *MemoryStore) RevokeTeamJoinLink(code string, teamID int64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	link, ok := m.joinLinks[code]
+	if !ok || link.Team != teamID || link.revoked {
+		return errJoinLinkNotFound
+	}
+	link.revoked = true
+	return nil
+}
+
+// RedeemTeamJoinLink counts a use of the link and adds userID to the roster
+func (m *MemoryStore) RedeemTeamJoinLink(code string, userID int64) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	link, ok := m.joinLinks[code]
+	if !ok {
+		return 0, errJoinLinkNotFound
+	}
+	now := time.Now().Unix()
+	if link.revoked || (link.MaxUses != 0 && link.Uses >= link.MaxUses) || (link.ExpiresAt != 0 && link.ExpiresAt <= now) || m.teams[link.Team].DisbandedAt != 0 {
+		return 0, &TxError{Op: "redeem join link", Err: errJoinLinkInvalid}
+	}
+	if err := m.addToRoster(userID, link.Team, roleMember); err != nil {
+		return 0, &TxError{Op: "redeem join link", Err: err}
+	}
+	link.Uses++
+	return link.Team, nil
+}
+
+// NewTeamJoinRequest creates a pending join request if the team accepts them, the player is
+// not on its roster and is under the limit
+func (m *MemoryStore) NewTeamJoinRequest(req *TeamJoinRequest) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	t, ok := m.teams[req.Team]
+	if !ok || t.DisbandedAt != 0 {
+		return errTeamNotFound
+	}
+	if !t.AcceptsRequests {
+		return errJoinRequestsClosed
+	}
+	if _, ok := m.users[req.User]; !ok {
+		return &TxError{Op: "create join request", Err: errUserNotFound}
+	}
+	if _, ok := m.roster[req.Team][req.User]; ok {
+		return &TxError{Op: "create join request", Err: errAlreadyOnRoster}
+	}
+	open := 0
+	for _, existing := range m.joinRequests {
+		if existing.User != req.User || existing.Status != joinRequestPending {
+			continue
+		}
+		if existing.Team == req.Team {
+			return &TxError{Op: "create join request", Err: errJoinRequestExists}
+		}
+		open++
+	}
+	if open >= maxOpenJoinRequests {
+		return &TxError{Op: "create join request", Err: errJoinRequestLimit}
+	}
+	req.ID = m.nextID("team_join_request")
+	req.Status = joinRequestPending
+	req.CreatedAt = time.Now().Unix()
+	stored := *req
+	m.joinRequests[stored.ID] = &stored
+	return nil
+}
+
+// GetTeamJoinRequests returns the pending join requests of a team, oldest first
+func (m *MemoryStore) GetTeamJoinRequests(teamID int64) ([]*TeamJoinRequest, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var reqs []*TeamJoinRequest
+	for _, stored := range m.joinRequests {
+		if stored.Team == teamID && stored.Status == joinRequestPending {
+			req := *stored
+			reqs = append(reqs, &req)
+		}
+	}
+	sort.Slice(reqs, func(i, j int) bool {
+		if reqs[i].CreatedAt != reqs[j].CreatedAt {
+			return reqs[i].CreatedAt < reqs[j].CreatedAt
+		}
+		return reqs[i].ID < reqs[j].ID
+	})
+	return reqs, nil
+}
+
+// DecideTeamJoinRequest approves or rejects a pending join request,
+// approving adds the player to the roster
+func (m *MemoryStore) DecideTeamJoinRequest(requestID, teamID int64, approve bool) (*TeamJoinRequest, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	stored, ok := m.joinRequests[requestID]
+	if !ok || stored.Team != teamID || stored.Status != joinRequestPending {
+		return nil, &TxError{Op: "decide join request", Err: errJoinRequestNotFound}
+	}
+	status := joinRequestRejected
+	if approve {
+		if err := m.addToRoster(stored.User, teamID, roleMember); err != nil {
+			return nil, &TxError{Op: "decide join request", Err: err}
+		}
+		status = joinRequestApproved
+	}
+	stored.Status = status
+	stored.DecidedAt = time.Now().Unix()
+	req := *stored
+	return &req, nil
+}
+
+// accountTaken reports whether puuid is linked to any user
+func (m *MemoryStore) accountTaken(puuid string) bool {
+	for _, a := range m.accounts {
+		if a.PUUID == puuid {
+			return true
+		}
+	}
+	return false
+}
+
+// insertAccount stores a linked account, making it primary if the user has none on its region
+func (m *MemoryStore) insertAccount(account *LinkedAccount) {
+	account.Primary = true
+	for _, a := range m.accounts {
+		if a.UserID == account.UserID && a.Region == account.Region && a.Primary {
+			account.Primary = false
+		}
+	}
+	account.ID = m.nextID("linked_account")
+	stored := *account
+	m.accounts[stored.ID] = &stored
+}
+
+// primaryAccount returns the primary account of userID on region or nil, the caller must hold m.mu
+func (m *MemoryStore) primaryAccount(userID int64, region string) *LinkedAccount {
+	for _, a := range m.accounts {
+		if a.UserID == userID && a.Region == region && a.Primary {
+			return a
+		}
+	}
+	return nil
+}
+
+// GetSummonerOwner returns the ID of the user puuid is linked to on any region or errUserNotFound
+func (m *MemoryStore) GetSummonerOwner(puuid string) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, a := range m.accounts {
+		if a.PUUID == puuid {
+			return a.UserID, nil
+		}
+	}
+	return 0, errUserNotFound
+}
+
+// LinkAccount links the summoner of a verification to its user and removes the verification
+func (m *MemoryStore) LinkAccount(v *SummonerVerification) (*LinkedAccount, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	now := time.Now().Unix()
+	var account *LinkedAccount
+	for _, a := range m.accounts {
+		if a.PUUID != v.PUUID {
+			continue
+		}
+		if a.UserID != v.UserID {
+			return nil, &TxError{Op: "link account", Err: errSummonerAlreadyLinked}
+		}
+		if a.Region != v.Region {
+			return nil, &TxError{Op: "link account", Err: errSummonerOtherRegion}
+		}
+		a.SummonerID, a.RiotID, a.VerifiedAt = v.SummonerID, v.RiotID, now
+		account = a
+	}
+	if account == nil {
+		if _, ok := m.users[v.UserID]; !ok {
+			return nil, &TxError{Op: "link account", Err: errUserNotFound}
+		}
+		account = &LinkedAccount{UserID: v.UserID, PUUID: v.PUUID, SummonerID: v.SummonerID, Region: v.Region, RiotID: v.RiotID, VerifiedAt: now, CreatedAt: now}
+		m.insertAccount(account)
+	}
+	delete(m.verifications, v.UserID)
+	copied := *account
+	return &copied, nil
+}
+
+// GetLinkedAccounts returns the accounts linked to userID by region, primary accounts first
+func (m *MemoryStore) GetLinkedAccounts(userID int64) ([]*LinkedAccount, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var accounts []*LinkedAccount
+	for _, a := range m.accounts {
+		if a.UserID == userID {
+			copied := *a
+			accounts = append(accounts, &copied)
+		}
+	}
+	attachRanks(accounts, m.currentRanks(func(a *LinkedAccount) bool { return a.UserID == userID }))
+	sort.Slice(accounts, func(i, j int) bool {
+		a, b := accounts[i], accounts[j]
+		if a.Region != b.Region {
+			return a.Region < b.Region
+		}
+		if a.Primary != b.Primary {
+			return a.Primary
+		}
+		return a.ID < b.ID
+	})
+	return accounts, nil
+}
+
+// SetPrimaryAccount makes accountID the primary account of userID on its region
+func (m *MemoryStore) SetPrimaryAccount(userID, accountID int64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	account, ok := m.accounts[accountID]
+	if !ok || account.UserID != userID {
+		return &TxError{Op: "set primary account", Err: errLinkedAccountNotFound}
+	}
+	for _, a := range m.accounts {
+		if a.UserID == userID && a.Region == account.Region {
+			a.Primary = a.ID == accountID
+		}
+	}
+	return nil
+}
+
+// UnlinkAccount removes a linked account of userID, the oldest remaining
+// account on its region becomes primary if it was
+func (m *MemoryStore) UnlinkAccount(userID, accountID int64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	account, ok := m.accounts[accountID]
+	if !ok || account.UserID != userID {
+		return &TxError{Op: "unlink account", Err: errLinkedAccountNotFound}
+	}
+	delete(m.accounts, accountID)
+	m.deleteRankSnapshots()
+	if !account.Primary {
+		return nil
+	}
+	var next *LinkedAccount
+	for _, a := range m.accounts {
+		if a.UserID == userID && a.Region == account.Region && (next == nil || a.ID < next.ID) {
+			next = a
+		}
+	}
+	if next != nil {
+		next.Primary = true
+	}
+	return nil
+}
+
+// UnlinkAllAccounts removes every account and any pending verification of userID
+func (m *MemoryStore) UnlinkAllAccounts(userID int64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.verifications, userID)
+	n := 0
+	for id, a := range m.accounts {
+		if a.UserID == userID {
+			delete(m.accounts, id)
+			n++
+		}
+	}
+	if n == 0 {
+		return &TxError{Op: "unlink accounts", Err: errSummonerNotLinked}
+	}
+	m.deleteRankSnapshots()
+	return nil
+}
+
+// deleteRankSnapshots removes the snapshots of unlinked accounts
+func (m *MemoryStore) deleteRankSnapshots() {
+	kept := m.rankSnapshots[:0]
+	for _, s := range m.rankSnapshots {
+		if _, ok := m.accounts[s.AccountID]; ok {
+			kept = append(kept, s)
+		}
+	}
+	m.rankSnapshots = kept
+}
+
+// snapshot returns a copy of s with the user and region of its account
+func (m *MemoryStore) snapshot(s *RankSnapshot) *RankSnapshot {
+	copied := *s
+	account := m.accounts[s.AccountID]
+	copied.UserID, copied.Region = account.UserID, account.Region
+	return &copied
+}
+
+// currentRanks returns the latest ranked snapshot of each queue of the accounts matching pred
+func (m *MemoryStore) currentRanks(pred func(*LinkedAccount) bool) []*RankSnapshot {
+	type key struct {
+		accountID int64
+		queue     string
+	}
+	latest := map[key]*RankSnapshot{}
+	for _, s := range m.rankSnapshots {
+		if pred(m.accounts[s.AccountID]) {
+			latest[key{s.AccountID, s.Queue}] = s
+		}
+	}
+	var snapshots []*RankSnapshot
+	for _, s := range latest {
+		if s.Tier != tierUnranked {
+			snapshots = append(snapshots, m.snapshot(s))
+		}
+	}
+	sort.Slice(snapshots, func(i, j int) bool {
+		a, b := snapshots[i], snapshots[j]
+		if a.AccountID != b.AccountID {
+			return a.AccountID < b.AccountID
+		}
+		return a.Queue < b.Queue
+	})
+	return snapshots
+}
+
+// GetStaleAccounts returns up to limit linked accounts last synced before before, least recent first
+func (m *MemoryStore) GetStaleAccounts(before int64, limit int) ([]*LinkedAccount, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var accounts []*LinkedAccount
+	for _, a := range m.accounts {
+		if a.SyncedAt < before {
+			copied := *a
+			accounts = append(accounts, &copied)
+		}
+	}
+	sort.Slice(accounts, func(i, j int) bool {
+		a, b := accounts[i], accounts[j]
+		if a.SyncedAt != b.SyncedAt {
+			return a.SyncedAt < b.SyncedAt
+		}
+		return a.ID < b.ID
+	})
+	if len(accounts) > limit {
+		accounts = accounts[:limit]
+	}
+	return accounts, nil
+}
+
+// SaveAccountSync stores the profile of a linked account and snapshots each ranked
+// queue whose rank changed since its last snapshot
+func (m *MemoryStore) SaveAccountSync(sync *AccountSync) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	account, ok := m.accounts[sync.AccountID]
+	if !ok {
+		return &TxError{Op: "save account sync", Err: errLinkedAccountNotFound}
+	}
+	account.SummonerID = sync.SummonerID
+	account.ProfileIconID = sync.ProfileIconID
+	account.SummonerLevel = sync.SummonerLevel
+	account.SyncedAt = sync.SyncedAt
+	if sync.Ranks == nil {
+		return nil
+	}
+	for _, rank := range syncedRanks(sync) {
+		var last *RankSnapshot
+		for _, s := range m.rankSnapshots {
+			if s.AccountID == sync.AccountID && s.Queue == rank.Queue {
+				last = s
+			}
+		}
+		if (last == nil && rank.Tier == tierUnranked) || (last != nil && last.Rank == *rank) {
+			continue
+		}
+		m.rankSnapshots = append(m.rankSnapshots, &RankSnapshot{
+			ID:        m.nextID("rank_snapshot"),
+			AccountID: sync.AccountID,
+			Rank:      *rank,
+			TakenAt:   sync.SyncedAt,
+		})
+	}
+	return nil
+}
+
+// GetCurrentRanks returns the current ranks of the primary accounts of userIDs
+func (m *MemoryStore) GetCurrentRanks(userIDs []int64) ([]*RankSnapshot, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	users := map[int64]bool{}
+	for _, id := range userIDs {
+		users[id] = true
+	}
+	return m.currentRanks(func(a *LinkedAccount) bool { return users[a.UserID] && a.Primary }), nil
+}
+
+// GetRosterRanks returns the current ranks of the primary accounts the players on each of teamIDs
+// have on the team's region by team
+func (m *MemoryStore) GetRosterRanks(teamIDs []int64) (map[int64][]*RankSnapshot, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	ranks := map[int64][]*RankSnapshot{}
+	for _, teamID := range teamIDs {
+		team, ok := m.teams[teamID]
+		if !ok {
+			continue
+		}
+		roster := m.roster[teamID]
+		snapshots := m.currentRanks(func(a *LinkedAccount) bool {
+			_, ok := roster[a.UserID]
+			return ok && a.Primary && a.Region == team.Region
+		})
+		if len(snapshots) > 0 {
+			ranks[teamID] = snapshots
+		}
+	}
+	return ranks, nil
+}
+
+// GetRankHistory returns up to limit rank snapshots of the accounts linked to userID, newest first
+func (m *MemoryStore) GetRankHistory(userID int64, region, queue string, limit int) ([]*RankSnapshot, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var history []*RankSnapshot
+	for i := len(m.rankSnapshots) - 1; i >= 0 && len(history) < limit; i-- {
+		s := m.rankSnapshots[i]
+		account := m.accounts[s.AccountID]
+		if account.UserID != userID || region != "" && account.Region != region || queue != "" && s.Queue != queue {
+			continue
+		}
+		history = append(history, m.snapshot(s))
+	}
+	return history, nil
+}
@@ -0,0 +1,416 @@
+package main
+
+import (
+	"embed"
+	"errors"
+	"fmt"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// migrationFiles holds the schema migrations of every dialect,
+// stored as migrations/<driver>/<version>_<name>.up.sql and .down.sql
+//
+//go:embed migrations
+var migrationFiles embed.FS
+
+var errNoMigrations = errors.New("no migrations applied")
+
+// migration is one versioned schema change and how to revert it
+type migration struct {
+	Version int64
+	Name    string
+	Up      string
+	Down    string
+}
+
+// MigrationStatus reports whether a migration has been applied
+// AppliedAt is 0 for pending migrations
+type MigrationStatus struct {
+	Version   int64
+	Name      string
+	AppliedAt int64
+}
+
+// loadMigrations reads the migrations of a dialect ordered by version
+func loadMigrations(d *dialect) ([]*migration, error) {
+	dir := path.Join("migrations", d.driver)
+	entries, err := migrationFiles.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	byVersion := map[int64]*migration{}
+	for _, entry := range entries {
+		file := entry.Name()
+		var base string
+		up := strings.HasSuffix(file, ".up.sql")
+		switch {
+		case up:
+			base = strings.TrimSuffix(file, ".up.sql")
+		case strings.HasSuffix(file, ".down.sql"):
+			base = strings.TrimSuffix(file, ".down.sql")
+		default:
+			continue
+		}
+		parts := strings.SplitN(base, "_", 2)
+		version, err := strconv.ParseInt(parts[0], 10, 64)
+		if err != nil || len(parts) != 2 {
+			return nil, fmt.Errorf("migration %s: name must be <version>_<name>", file)
+		}
+		contents, err := migrationFiles.ReadFile(path.Join(dir, file))
+		if err != nil {
+			return nil, err
+		}
+		m, ok := byVersion[version]
+		if !ok {
+			m = &migration{Version: version, Name: parts[1]}
+			byVersion[version] = m
+		}
+		if up {
+			m.Up = string(contents)
+		} else {
+			m.Down = string(contents)
+		}
+	}
+	migrations := make([]*migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.Up == "" || m.Down == "" {
+			return nil, fmt.Errorf("migration %d needs both an up and a down file", m.Version)
+		}
+		migrations = append(migrations, m)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
+
+// splitStatements splits a migration into statements that can be run one at a time
+// semicolons only end a statement outside of quotes, comments and postgres dollar quoted
+// bodies, comments are left out and quotes are escaped by doubling them as in standard sql
+func splitStatements(script string) []string {
+	var statements []string
+	var stmt strings.Builder
+	end := func() {
+		if s := strings.TrimSpace(stmt.String()); s != "" {
+			statements = append(statements, s)
+		}
+		stmt.Reset()
+	}
+	for i := 0; i < len(script); i++ {
+		c := script[i]
+		switch {
+		case c == ';':
+			end()
+		case c == '\'', c == '"', c == '`':
+			n := quotedLength(script[i:], string(c))
+			stmt.WriteString(script[i : i+n])
+			i += n - 1
+		case strings.HasPrefix(script[i:], "--"):
+			n := strings.IndexByte(script[i:], '\n')
+			if n < 0 {
+				n = len(script) - i
+			}
+			stmt.WriteByte(' ')
+			i += n - 1
+		case strings.HasPrefix(script[i:], "/*"):
+			n := strings.Index(script[i+2:], "*/")
+			if n < 0 {
+				n = len(script) - i
+			} else {
+				n += 4
+			}
+			stmt.WriteByte(' ')
+			i += n - 1
+		case c == '$' && dollarTag(script[i:]) != "":
+			n := quotedLength(script[i:], dollarTag(script[i:]))
+			stmt.WriteString(script[i : i+n])
+			i += n - 1
+		default:
+			stmt.WriteByte(c)
+		}
+	}
+	end()
+	return statements
+}
+
+// quotedLength returns the length of the quoted text at the start of s, delimited by quote
+// on both ends, a doubled single character quote is part of the text
+// unterminated text runs to the end of s
+func quotedLength(s, quote string) int {
+	i := len(quote)
+	for {
+		n := strings.Index(s[i:], quote)
+		if n < 0 {
+			return len(s)
+		}
+		i += n + len(quote)
+		if len(quote) > 1 || !strings.HasPrefix(s[i:], quote) {
+			return i
+		}
+		i += len(quote)
+	}
+}
+
+// dollarTag returns the $tag$ or $$ that opens a postgres dollar quoted string at the start of s,
+// or "" if s doesn't start with one
+func dollarTag(s string) string {
+	for i := 1; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == '$':
+			return s[:i+1]
+		case c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || i > 1 && c >= '0' && c <= '9':
+		default:
+			return ""
+		}
+	}
+	return ""
+}
+
+// appliedMigrations returns the applied versions and when they were applied,
+// creating the schema_migrations table if needed
+func (m *SQLStore) appliedMigrations() (map[int64]int64, error) {
+	_, err := m.db.Exec("CREATE TABLE IF NOT EXISTS schema_migrations (version BIGINT NOT NULL PRIMARY KEY, name VARCHAR(255) NOT NULL, appliedAt BIGINT NOT NULL)")
+	if err != nil {
+		return nil, err
+	}
+	applied := map[int64]int64{}
+	rows, err := m.db.Query("SELECT version, appliedAt FROM schema_migrations")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var version, appliedAt int64
+		err := rows.Scan(&version, &appliedAt)
+		if err != nil {
+			return nil, err
+		}
+		applied[version] = appliedAt
+	}
+	err = rows.Err()
+	if err != nil {
+		return nil, err
+	}
+	return applied, nil
+}
+
+// runMigration runs the statements of one migration step and records it in schema_migrations
+// the step is rolled back as a whole if a statement fails, except on databases without
+// transactional ddl where the error says which statements were already committed
+func (m *SQLStore) runMigration(mig *migration, up bool) error {
+	op := fmt.Sprintf("migrate down %04d_%s", mig.Version, mig.Name)
+	script := mig.Down
+	if up {
+		op = fmt.Sprintf("migrate up %04d_%s", mig.Version, mig.Name)
+		script = mig.Up
+	}
+	if m.db.dialect.rebuildsTables {
+		// foreign_keys can't be changed inside a transaction, and dropping a table
+		// while they are on deletes its rows as if they were referenced
+		if _, err := m.db.Exec("PRAGMA foreign_keys = OFF"); err != nil {
+			return err
+		}
+		defer m.db.Exec("PRAGMA foreign_keys = ON")
+	}
+	return m.withTx(op, func(tx *sqlTx) error {
+		statements := splitStatements(script)
+		for i, stmt := range statements {
+			if _, err := tx.Exec(stmt); err != nil {
+				if !m.db.dialect.transactionalDDL && i > 0 {
+					return fmt.Errorf("statement %d of %d: %w (%s commits schema changes as they run, "+
+						"revert the %d statements before it by hand before migrating again)", i+1, len(statements), err, m.db.dialect.driver, i)
+				}
+				return err
+			}
+		}
+		if m.db.dialect.rebuildsTables {
+			if err := checkForeignKeys(tx); err != nil {
+				return err
+			}
+		}
+		if up {
+			_, err := tx.Exec("INSERT INTO schema_migrations(version,name,appliedAt) VALUES(?,?,?)", mig.Version, mig.Name, time.Now().Unix())
+			return err
+		}
+		_, err := tx.Exec("DELETE FROM schema_migrations WHERE version=?", mig.Version)
+		return err
+	})
+}
+
+// checkForeignKeys returns an error if a migration run with foreign keys off left rows
+// referencing rows that don't exist
+func checkForeignKeys(tx *sqlTx) error {
+	rows, err := tx.Query("PRAGMA foreign_key_check")
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	if rows.Next() {
+		return errors.New("migration breaks foreign key constraints")
+	}
+	return rows.Err()
+}
+
+// MigrateUp applies every pending migration in order and returns the ones applied
+func (m *SQLStore) MigrateUp() ([]MigrationStatus, error) {
+	migrations, err := loadMigrations(m.db.dialect)
+	if err != nil {
+		return nil, err
+	}
+	applied, err := m.appliedMigrations()
+	if err != nil {
+		return nil, err
+	}
+	var done []MigrationStatus
+	for _, mig := range migrations {
+		if _, ok := applied[mig.Version]; ok {
+			continue
+		}
+		err := m.runMigration(mig, true)
+		if err != nil {
+			return done, err
+		}
+		done = append(done, MigrationStatus{Version: mig.Version, Name: mig.Name, AppliedAt: time.Now().Unix()})
+	}
+	return done, nil
+}
+
+// MigrateDown reverts the most recently applied migration and returns it
+func (m *SQLStore) MigrateDown() (*MigrationStatus, error) {
+	migrations, err := loadMigrations(m.db.dialect)
+	if err != nil {
+		return nil, err
+	}
+	applied, err := m.appliedMigrations()
+	if err != nil {
+		return nil, err
+	}
+	for i := len(migrations) - 1; i >= 0; i-- {
+		mig := migrations[i]
+		if _, ok := applied[mig.Version]; !ok {
+			continue
+		}
+		err := m.runMigration(mig, false)
+		if err != nil {
+			return nil, err
+		}
+		return &MigrationStatus{Version: mig.Version, Name: mig.Name}, nil
+	}
+	return nil, errNoMigrations
+}
+
+// MigrationStatus lists every migration of the dialect and when it was applied
+func (m *SQLStore) MigrationStatus() ([]MigrationStatus, error) {
+	migrations, err := loadMigrations(m.db.dialect)
+	if err != nil {
+		return nil, err
+	}
+	applied, err := m.appliedMigrations()
+	if err != nil {
+		return nil, err
+	}
+	statuses := make([]MigrationStatus, 0, len(migrations))
+	for _, mig := range migrations {
+		statuses = append(statuses, MigrationStatus{Version: mig.Version, Name: mig.Name, AppliedAt: applied[mig.Version]})
+	}
+	return statuses, nil
+}
+
+// MigrateBaseline records the first migration as applied without running it, for databases
+// whose tables were created by hand before migrations were tracked, later migrations are
+// left pending for migrate up
+func (m *SQLStore) MigrateBaseline() (*MigrationStatus, error) {
+	migrations, err := loadMigrations(m.db.dialect)
+	if err != nil {
+		return nil, err
+	}
+	applied, err := m.appliedMigrations()
+	if err != nil {
+		return nil, err
+	}
+	if len(applied) > 0 {
+		return nil, errors.New("migrations are already tracked, baseline is only for databases created before them")
+	}
+	if _, err := m.db.Exec("SELECT id FROM account WHERE 1=0"); err != nil {
+		return nil, fmt.Errorf("no existing schema to baseline, run migrate up instead: %w", err)
+	}
+	base := migrations[0]
+	now := time.Now().Unix()
+	_, err = m.db.Exec("INSERT INTO schema_migrations(version,name,appliedAt) VALUES(?,?,?)", base.Version, base.Name, now)
+	if err != nil {
+		return nil, err
+	}
+	return &MigrationStatus{Version: base.Version, Name: base.Name, AppliedAt: now}, nil
+}
+
+// CheckSchema returns an error if any migration built into the binary has not been applied
+func (m *SQLStore) CheckSchema() error {
+	statuses, err := m.MigrationStatus()
+	if err != nil {
+		return err
+	}
+	var pending []string
+	for _, status := range statuses {
+		if status.AppliedAt == 0 {
+			pending = append(pending, fmt.Sprintf("%04d_%s", status.Version, status.Name))
+		}
+	}
+	if len(pending) > 0 && statuses[0].AppliedAt == 0 {
+		return fmt.Errorf("database schema is not tracked, pending migrations: %s "+
+			"(run migrate up on a new database, or migrate baseline then migrate up if its tables already exist)", strings.Join(pending, ", "))
+	}
+	if len(pending) > 0 {
+		return fmt.Errorf("database schema is behind, pending migrations: %s (run migrate up)", strings.Join(pending, ", "))
+	}
+	return nil
+}
+
+// runMigrateCommand handles the migrate up|down|status|baseline subcommand
+func runMigrateCommand(store *SQLStore, args []string) error {
+	if len(args) != 1 {
+		return errors.New("usage: migrate up|down|status|baseline")
+	}
+	switch args[0] {
+	case "up":
+		done, err := store.MigrateUp()
+		for _, status := range done {
+			fmt.Printf("applied %04d_%s\n", status.Version, status.Name)
+		}
+		if err != nil {
+			return err
+		}
+		if len(done) == 0 {
+			fmt.Println("schema is up to date")
+		}
+	case "down":
+		status, err := store.MigrateDown()
+		if err != nil {
+			return err
+		}
+		fmt.Printf("reverted %04d_%s\n", status.Version, status.Name)
+	case "baseline":
+		status, err := store.MigrateBaseline()
+		if err != nil {
+			return err
+		}
+		fmt.Printf("recorded %04d_%s as applied, run migrate up for the rest\n", status.Version, status.Name)
+	case "status":
+		statuses, err := store.MigrationStatus()
+		if err != nil {
+			return err
+		}
+		for _, status := range statuses {
+			state := "pending"
+			if status.AppliedAt != 0 {
+				state = "applied " + time.Unix(status.AppliedAt, 0).UTC().Format(time.RFC3339)
+			}
+			fmt.Printf("%04d_%s\t%s\n", status.Version, status.Name, state)
+		}
+	default:
+		return errors.New("usage: migrate up|down|status|baseline")
+	}
+	return nil
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSplitStatements(t *testing.T) {
+	tests := []struct {
+		name   string
+		script string
+		want   []string
+	}{
+		{"statements", "CREATE TABLE a (id INT);\n\nCREATE TABLE b (id INT);\n", []string{"CREATE TABLE a (id INT)", "CREATE TABLE b (id INT)"}},
+		{"no trailing semicolon", "DROP TABLE a", []string{"DROP TABLE a"}},
+		{"quoted semicolon", "INSERT INTO a VALUES('x;y');", []string{"INSERT INTO a VALUES('x;y')"}},
+		{"doubled quote", "INSERT INTO a VALUES('it''s; fine');", []string{"INSERT INTO a VALUES('it''s; fine')"}},
+		{"quoted identifiers", "SELECT \"a;b\", `c;d` FROM a;", []string{"SELECT \"a;b\", `c;d` FROM a"}},
+		{"line comment", "-- drop it; later\nDROP TABLE a; -- done;\n", []string{"DROP TABLE a"}},
+		{"block comment", "/* one; */ DROP TABLE a; /* two;\nthree; */", []string{"DROP TABLE a"}},
+		{"dollar quoted", "CREATE FUNCTION f() RETURNS INT AS $body$ SELECT 1; $body$ LANGUAGE sql; SELECT $$;$$;",
+			[]string{"CREATE FUNCTION f() RETURNS INT AS $body$ SELECT 1; $body$ LANGUAGE sql", "SELECT $$;$$"}},
+		{"only comments", "-- nothing to run;\n", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := splitStatements(tt.script); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("splitStatements(%q) = %q, want %q", tt.script, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMigrateSQLite(t *testing.T) {
+	db, err := openSQLite(filepath.Join(t.TempDir(), "migrate.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	store := NewSQLStore(db, sqliteDialect)
+
+	migrations, err := loadMigrations(sqliteDialect)
+	if err != nil {
+		t.Fatal(err)
+	}
+	applied, err := store.MigrateUp()
+	if err != nil {
+		t.Fatalf("migrate up: %v", err)
+	}
+	if len(applied) != len(migrations) {
+		t.Fatalf("applied %d migrations, want %d", len(applied), len(migrations))
+	}
+	if err := store.CheckSchema(); err != nil {
+		t.Fatal(err)
+	}
+
+	// the schema is used while migrated so the table rebuilds have rows to carry over
+	userID, err := store.NewUser(&User{Username: "player", Password: "hash", Email: "player@example.com"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	team := &Team{Name: "Alpha Squad", Captain: userID, Region: "na1"}
+	if err := store.CreateTeam(team); err != nil {
+		t.Fatal(err)
+	}
+
+	for i := len(migrations) - 1; i > 0; i-- {
+		reverted, err := store.MigrateDown()
+		if err != nil {
+			t.Fatalf("migrate down %04d_%s: %v", migrations[i].Version, migrations[i].Name, err)
+		}
+		if reverted.Version != migrations[i].Version {
+			t.Fatalf("reverted %d, want %d", reverted.Version, migrations[i].Version)
+		}
+	}
+	if _, err := store.MigrateUp(); err != nil {
+		t.Fatalf("migrate up again: %v", err)
+	}
+	got, err := store.GetTeam(team.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Name != team.Name || got.Captain != userID {
+		t.Errorf("team after migrating down and up = %+v", got)
+	}
+}
+
+func TestMigrateBaseline(t *testing.T) {
+	db, err := openSQLite(filepath.Join(t.TempDir(), "baseline.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	store := NewSQLStore(db, sqliteDialect)
+	if _, err := store.MigrateBaseline(); err == nil {
+		t.Fatal("baseline of an empty database succeeded")
+	}
+
+	// a database whose tables were created before migrations were tracked
+	migrations, err := loadMigrations(sqliteDialect)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, stmt := range splitStatements(migrations[0].Up) {
+		if _, err := db.Exec(stmt); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := store.MigrateUp(); err == nil {
+		t.Fatal("migrate up over existing tables succeeded")
+	}
+	if err := store.CheckSchema(); err == nil || !strings.Contains(err.Error(), "migrate baseline") {
+		t.Errorf("check schema: %v, want a hint to baseline", err)
+	}
+
+	base, err := store.MigrateBaseline()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if base.Version != migrations[0].Version {
+		t.Errorf("baseline recorded %d, want %d", base.Version, migrations[0].Version)
+	}
+	if _, err := store.MigrateBaseline(); err == nil {
+		t.Error("baseline ran twice")
+	}
+	applied, err := store.MigrateUp()
+	if err != nil {
+		t.Fatalf("migrate up after baseline: %v", err)
+	}
+	if len(applied) != len(migrations)-1 {
+		t.Errorf("applied %d migrations after baseline, want %d", len(applied), len(migrations)-1)
+	}
+	if err := store.CheckSchema(); err != nil {
+		t.Error(err)
+	}
+}
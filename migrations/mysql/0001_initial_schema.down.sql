@@ -0,0 +1,8 @@
+DROP TABLE team_join_request;
+DROP TABLE team_join_link;
+DROP TABLE team_invite;
+DROP TABLE roster_removal;
+DROP TABLE roster;
+DROP TABLE team;
+DROP TABLE session;
+DROP TABLE account;
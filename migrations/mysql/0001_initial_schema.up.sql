@@ -0,0 +1,91 @@
+CREATE TABLE account (
+	id BIGINT NOT NULL AUTO_INCREMENT PRIMARY KEY,
+	username VARCHAR(64) NOT NULL UNIQUE,
+	password VARCHAR(255) NOT NULL,
+	email VARCHAR(255) NOT NULL DEFAULT '',
+	summonerId INT NOT NULL DEFAULT 0
+) ENGINE=InnoDB;
+
+CREATE TABLE session (
+	id VARCHAR(32) NOT NULL PRIMARY KEY,
+	userId BIGINT NOT NULL,
+	tokenHash CHAR(64) NOT NULL,
+	userAgent VARCHAR(255) NOT NULL DEFAULT '',
+	createdAt BIGINT NOT NULL,
+	lastUsedAt BIGINT NOT NULL,
+	expiresAt BIGINT NOT NULL,
+	revokedAt BIGINT NULL,
+	INDEX session_user (userId),
+	FOREIGN KEY (userId) REFERENCES account(id)
+) ENGINE=InnoDB;
+
+CREATE TABLE team (
+	id BIGINT NOT NULL AUTO_INCREMENT PRIMARY KEY,
+	name VARCHAR(64) NOT NULL,
+	captain BIGINT NOT NULL,
+	acceptsRequests BOOLEAN NOT NULL DEFAULT FALSE,
+	disbandedAt BIGINT NULL,
+	INDEX team_name (name),
+	FOREIGN KEY (captain) REFERENCES account(id)
+) ENGINE=InnoDB;
+
+CREATE TABLE roster (
+	teamID BIGINT NOT NULL,
+	userID BIGINT NOT NULL,
+	role VARCHAR(16) NOT NULL,
+	joinedAt BIGINT NOT NULL,
+	PRIMARY KEY (teamID, userID),
+	INDEX roster_user (userID),
+	FOREIGN KEY (teamID) REFERENCES team(id),
+	FOREIGN KEY (userID) REFERENCES account(id)
+) ENGINE=InnoDB;
+
+CREATE TABLE roster_removal (
+	id BIGINT NOT NULL AUTO_INCREMENT PRIMARY KEY,
+	teamId BIGINT NOT NULL,
+	userId BIGINT NOT NULL,
+	removedBy BIGINT NOT NULL,
+	reason VARCHAR(255) NOT NULL DEFAULT '',
+	removedAt BIGINT NOT NULL,
+	FOREIGN KEY (teamId) REFERENCES team(id),
+	FOREIGN KEY (userId) REFERENCES account(id),
+	FOREIGN KEY (removedBy) REFERENCES account(id)
+) ENGINE=InnoDB;
+
+CREATE TABLE team_invite (
+	id BIGINT NOT NULL AUTO_INCREMENT PRIMARY KEY,
+	teamId BIGINT NOT NULL,
+	invitee BIGINT NOT NULL,
+	status VARCHAR(16) NOT NULL,
+	createdAt BIGINT NOT NULL,
+	expiresAt BIGINT NOT NULL,
+	INDEX team_invite_invitee (invitee, status),
+	FOREIGN KEY (teamId) REFERENCES team(id),
+	FOREIGN KEY (invitee) REFERENCES account(id)
+) ENGINE=InnoDB;
+
+CREATE TABLE team_join_link (
+	code VARCHAR(32) NOT NULL PRIMARY KEY,
+	teamId BIGINT NOT NULL,
+	createdBy BIGINT NOT NULL,
+	maxUses INT NOT NULL DEFAULT 0,
+	uses INT NOT NULL DEFAULT 0,
+	createdAt BIGINT NOT NULL,
+	expiresAt BIGINT NOT NULL DEFAULT 0,
+	revokedAt BIGINT NULL,
+	FOREIGN KEY (teamId) REFERENCES team(id),
+	FOREIGN KEY (createdBy) REFERENCES account(id)
+) ENGINE=InnoDB;
+
+CREATE TABLE team_join_request (
+	id BIGINT NOT NULL AUTO_INCREMENT PRIMARY KEY,
+	teamId BIGINT NOT NULL,
+	userId BIGINT NOT NULL,
+	message VARCHAR(500) NOT NULL DEFAULT '',
+	status VARCHAR(16) NOT NULL,
+	createdAt BIGINT NOT NULL,
+	decidedAt BIGINT NULL,
+	INDEX team_join_request_user (userId, status),
+	FOREIGN KEY (teamId) REFERENCES team(id),
+	FOREIGN KEY (userId) REFERENCES account(id)
+) ENGINE=InnoDB;
@@ -0,0 +1,8 @@
+DROP INDEX account_puuid ON account;
+
+ALTER TABLE account
+	DROP COLUMN puuid,
+	DROP COLUMN summonerId,
+	DROP COLUMN region;
+
+ALTER TABLE account CHANGE legacySummonerId summonerId INT NOT NULL DEFAULT 0;
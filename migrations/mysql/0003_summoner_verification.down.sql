@@ -0,0 +1,7 @@
+DROP TABLE summoner_verification;
+
+ALTER TABLE account
+	DROP INDEX account_linked_puuid,
+	DROP COLUMN linkedPuuid;
+
+CREATE INDEX account_puuid ON account (puuid);
@@ -0,0 +1,18 @@
+-- unlinked accounts have an empty puuid, linkedPuuid is NULL for them so only linked accounts must be unique
+DROP INDEX account_puuid ON account;
+
+ALTER TABLE account
+	ADD COLUMN linkedPuuid VARCHAR(78) AS (NULLIF(puuid, '')) STORED,
+	ADD UNIQUE INDEX account_linked_puuid (linkedPuuid);
+
+CREATE TABLE summoner_verification (
+	userId BIGINT NOT NULL PRIMARY KEY,
+	riotId VARCHAR(64) NOT NULL,
+	puuid VARCHAR(78) NOT NULL,
+	summonerId VARCHAR(63) NOT NULL,
+	region VARCHAR(8) NOT NULL,
+	code VARCHAR(32) NOT NULL,
+	createdAt BIGINT NOT NULL,
+	expiresAt BIGINT NOT NULL,
+	FOREIGN KEY (userId) REFERENCES account(id)
+) ENGINE=InnoDB;
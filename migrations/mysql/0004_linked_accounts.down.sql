@@ -0,0 +1,16 @@
+-- only the oldest primary account of each user is kept, other linked accounts are lost
+ALTER TABLE account
+	ADD COLUMN puuid VARCHAR(78) NOT NULL DEFAULT '',
+	ADD COLUMN summonerId VARCHAR(63) NOT NULL DEFAULT '',
+	ADD COLUMN region VARCHAR(8) NOT NULL DEFAULT '';
+
+UPDATE account SET
+	puuid = COALESCE((SELECT puuid FROM linked_account WHERE userId = account.id AND isPrimary ORDER BY id LIMIT 1), ''),
+	summonerId = COALESCE((SELECT summonerId FROM linked_account WHERE userId = account.id AND isPrimary ORDER BY id LIMIT 1), ''),
+	region = COALESCE((SELECT region FROM linked_account WHERE userId = account.id AND isPrimary ORDER BY id LIMIT 1), '');
+
+ALTER TABLE account
+	ADD COLUMN linkedPuuid VARCHAR(78) AS (NULLIF(puuid, '')) STORED,
+	ADD UNIQUE INDEX account_linked_puuid (linkedPuuid);
+
+DROP TABLE linked_account;
@@ -0,0 +1,27 @@
+-- accounts linked before this migration become the primary account of their region,
+-- they were verified at signup or through the summoner endpoints but the time wasn't kept
+CREATE TABLE linked_account (
+	id BIGINT NOT NULL AUTO_INCREMENT PRIMARY KEY,
+	userId BIGINT NOT NULL,
+	puuid VARCHAR(78) NOT NULL,
+	summonerId VARCHAR(63) NOT NULL,
+	region VARCHAR(8) NOT NULL,
+	riotId VARCHAR(64) NOT NULL DEFAULT '',
+	isPrimary BOOLEAN NOT NULL DEFAULT FALSE,
+	verifiedAt BIGINT NOT NULL,
+	createdAt BIGINT NOT NULL,
+	UNIQUE (region, puuid),
+	INDEX linked_account_user (userId, region),
+	INDEX linked_account_puuid (puuid),
+	FOREIGN KEY (userId) REFERENCES account(id)
+) ENGINE=InnoDB;
+
+INSERT INTO linked_account (userId, puuid, summonerId, region, isPrimary, verifiedAt, createdAt)
+	SELECT id, puuid, summonerId, region, TRUE, 0, 0 FROM account WHERE puuid <> '';
+
+ALTER TABLE account
+	DROP INDEX account_linked_puuid,
+	DROP COLUMN linkedPuuid,
+	DROP COLUMN puuid,
+	DROP COLUMN summonerId,
+	DROP COLUMN region;
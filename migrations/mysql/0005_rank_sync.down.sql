@@ -0,0 +1,7 @@
+DROP TABLE rank_snapshot;
+
+ALTER TABLE linked_account
+	DROP INDEX linked_account_synced,
+	DROP COLUMN profileIconId,
+	DROP COLUMN summonerLevel,
+	DROP COLUMN syncedAt;
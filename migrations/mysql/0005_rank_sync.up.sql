@@ -0,0 +1,20 @@
+-- syncedAt 0 marks accounts never synced, the rank sync picks them up first
+ALTER TABLE linked_account
+	ADD COLUMN profileIconId INT NOT NULL DEFAULT 0,
+	ADD COLUMN summonerLevel BIGINT NOT NULL DEFAULT 0,
+	ADD COLUMN syncedAt BIGINT NOT NULL DEFAULT 0,
+	ADD INDEX linked_account_synced (syncedAt);
+
+CREATE TABLE rank_snapshot (
+	id BIGINT NOT NULL AUTO_INCREMENT PRIMARY KEY,
+	accountId BIGINT NOT NULL,
+	queue VARCHAR(32) NOT NULL,
+	tier VARCHAR(16) NOT NULL,
+	division VARCHAR(4) NOT NULL DEFAULT '',
+	leaguePoints INT NOT NULL DEFAULT 0,
+	wins INT NOT NULL DEFAULT 0,
+	losses INT NOT NULL DEFAULT 0,
+	takenAt BIGINT NOT NULL,
+	INDEX rank_snapshot_account (accountId, queue),
+	FOREIGN KEY (accountId) REFERENCES linked_account(id)
+) ENGINE=InnoDB;
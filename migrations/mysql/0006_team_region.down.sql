@@ -0,0 +1 @@
+ALTER TABLE team DROP COLUMN region;
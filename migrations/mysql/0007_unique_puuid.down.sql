@@ -0,0 +1,4 @@
+ALTER TABLE linked_account
+	DROP INDEX linked_account_puuid,
+	ADD UNIQUE (region, puuid),
+	ADD INDEX linked_account_puuid (puuid);
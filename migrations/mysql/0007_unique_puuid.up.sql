@@ -0,0 +1,6 @@
+-- a puuid identifies one riot account on every region, so it can only be linked once
+-- this fails while a puuid is linked on more than one region, unlink the extra accounts first
+ALTER TABLE linked_account
+	DROP INDEX region,
+	DROP INDEX linked_account_puuid,
+	ADD UNIQUE INDEX linked_account_puuid (puuid);
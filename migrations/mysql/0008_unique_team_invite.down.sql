@@ -0,0 +1,4 @@
+-- the foreign key on teamId uses team_invite_pair, so it gets an index of its own first
+ALTER TABLE team_invite
+	ADD INDEX team_invite_team (teamId),
+	DROP INDEX team_invite_pair;
@@ -0,0 +1,7 @@
+-- a team keeps one invite per invitee, sending a new invite replaces an expired one
+-- only the newest invite of each pair is kept
+DELETE FROM team_invite WHERE id NOT IN (
+	SELECT id FROM (SELECT MAX(id) AS id FROM team_invite GROUP BY teamId, invitee) AS newest
+);
+
+ALTER TABLE team_invite ADD UNIQUE INDEX team_invite_pair (teamId, invitee);
@@ -0,0 +1,3 @@
+ALTER TABLE team_join_request
+	DROP INDEX team_join_request_pending,
+	DROP COLUMN pendingTeamId;
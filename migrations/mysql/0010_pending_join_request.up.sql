@@ -0,0 +1,9 @@
+-- a player keeps one pending request per team, older duplicates are rejected
+-- mysql has no partial indexes, pendingTeamId is NULL for decided requests so only pending ones must be unique
+UPDATE team_join_request SET status = 'rejected', decidedAt = createdAt WHERE status = 'pending' AND id NOT IN (
+	SELECT id FROM (SELECT MAX(id) AS id FROM team_join_request WHERE status = 'pending' GROUP BY teamId, userId) AS newest
+);
+
+ALTER TABLE team_join_request
+	ADD COLUMN pendingTeamId BIGINT AS (CASE WHEN status = 'pending' THEN teamId END) STORED,
+	ADD UNIQUE INDEX team_join_request_pending (userId, pendingTeamId);
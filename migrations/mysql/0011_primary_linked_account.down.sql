@@ -0,0 +1,3 @@
+ALTER TABLE linked_account
+	DROP INDEX linked_account_primary,
+	DROP COLUMN primaryRegion;
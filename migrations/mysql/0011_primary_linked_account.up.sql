@@ -0,0 +1,9 @@
+-- a user keeps one primary account per region, the oldest of any duplicates stays primary
+-- mysql has no partial indexes, primaryRegion is NULL for other accounts so only primary ones must be unique
+UPDATE linked_account SET isPrimary = FALSE WHERE isPrimary = TRUE AND id NOT IN (
+	SELECT id FROM (SELECT MIN(id) AS id FROM linked_account WHERE isPrimary = TRUE GROUP BY userId, region) AS oldest
+);
+
+ALTER TABLE linked_account
+	ADD COLUMN primaryRegion VARCHAR(8) AS (CASE WHEN isPrimary THEN region END) STORED,
+	ADD UNIQUE INDEX linked_account_primary (userId, primaryRegion);
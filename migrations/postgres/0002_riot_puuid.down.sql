@@ -0,0 +1,8 @@
+DROP INDEX account_puuid;
+
+ALTER TABLE account
+	DROP COLUMN puuid,
+	DROP COLUMN summonerId,
+	DROP COLUMN region;
+
+ALTER TABLE account RENAME COLUMN legacySummonerId TO summonerId;
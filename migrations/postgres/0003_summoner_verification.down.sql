@@ -0,0 +1,5 @@
+DROP TABLE summoner_verification;
+
+DROP INDEX account_puuid;
+
+CREATE INDEX account_puuid ON account (puuid);
@@ -0,0 +1,15 @@
+DROP INDEX account_puuid;
+
+CREATE UNIQUE INDEX account_puuid ON account (puuid) WHERE puuid <> '';
+
+CREATE TABLE summoner_verification (
+	userId BIGINT NOT NULL PRIMARY KEY,
+	riotId VARCHAR(64) NOT NULL,
+	puuid VARCHAR(78) NOT NULL,
+	summonerId VARCHAR(63) NOT NULL,
+	region VARCHAR(8) NOT NULL,
+	code VARCHAR(32) NOT NULL,
+	createdAt BIGINT NOT NULL,
+	expiresAt BIGINT NOT NULL,
+	FOREIGN KEY (userId) REFERENCES account(id)
+);
@@ -0,0 +1,14 @@
+-- only the oldest primary account of each user is kept, other linked accounts are lost
+ALTER TABLE account
+	ADD COLUMN puuid VARCHAR(78) NOT NULL DEFAULT '',
+	ADD COLUMN summonerId VARCHAR(63) NOT NULL DEFAULT '',
+	ADD COLUMN region VARCHAR(8) NOT NULL DEFAULT '';
+
+UPDATE account SET
+	puuid = COALESCE((SELECT puuid FROM linked_account WHERE userId = account.id AND isPrimary ORDER BY id LIMIT 1), ''),
+	summonerId = COALESCE((SELECT summonerId FROM linked_account WHERE userId = account.id AND isPrimary ORDER BY id LIMIT 1), ''),
+	region = COALESCE((SELECT region FROM linked_account WHERE userId = account.id AND isPrimary ORDER BY id LIMIT 1), '');
+
+CREATE UNIQUE INDEX account_puuid ON account (puuid) WHERE puuid <> '';
+
+DROP TABLE linked_account;
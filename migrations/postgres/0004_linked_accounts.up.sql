@@ -0,0 +1,29 @@
+-- accounts linked before this migration become the primary account of their region,
+-- they were verified at signup or through the summoner endpoints but the time wasn't kept
+CREATE TABLE linked_account (
+	id BIGSERIAL PRIMARY KEY,
+	userId BIGINT NOT NULL,
+	puuid VARCHAR(78) NOT NULL,
+	summonerId VARCHAR(63) NOT NULL,
+	region VARCHAR(8) NOT NULL,
+	riotId VARCHAR(64) NOT NULL DEFAULT '',
+	isPrimary BOOLEAN NOT NULL DEFAULT FALSE,
+	verifiedAt BIGINT NOT NULL,
+	createdAt BIGINT NOT NULL,
+	UNIQUE (region, puuid),
+	FOREIGN KEY (userId) REFERENCES account(id)
+);
+
+CREATE INDEX linked_account_user ON linked_account (userId, region);
+
+CREATE INDEX linked_account_puuid ON linked_account (puuid);
+
+INSERT INTO linked_account (userId, puuid, summonerId, region, isPrimary, verifiedAt, createdAt)
+	SELECT id, puuid, summonerId, region, TRUE, 0, 0 FROM account WHERE puuid <> '';
+
+DROP INDEX account_puuid;
+
+ALTER TABLE account
+	DROP COLUMN puuid,
+	DROP COLUMN summonerId,
+	DROP COLUMN region;
@@ -0,0 +1,8 @@
+DROP TABLE rank_snapshot;
+
+DROP INDEX linked_account_synced;
+
+ALTER TABLE linked_account
+	DROP COLUMN profileIconId,
+	DROP COLUMN summonerLevel,
+	DROP COLUMN syncedAt;
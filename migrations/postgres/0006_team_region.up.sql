@@ -0,0 +1,3 @@
+-- teams play on one region, rosters show the summoner each member uses there
+-- teams created before regions were tracked default to na1 like other requests without a region
+ALTER TABLE team ADD COLUMN region VARCHAR(8) NOT NULL DEFAULT 'na1';
@@ -0,0 +1,5 @@
+DROP INDEX linked_account_puuid;
+
+CREATE INDEX linked_account_puuid ON linked_account (puuid);
+
+ALTER TABLE linked_account ADD UNIQUE (region, puuid);
@@ -0,0 +1,7 @@
+-- a puuid identifies one riot account on every region, so it can only be linked once
+-- this fails while a puuid is linked on more than one region, unlink the extra accounts first
+ALTER TABLE linked_account DROP CONSTRAINT linked_account_region_puuid_key;
+
+DROP INDEX linked_account_puuid;
+
+CREATE UNIQUE INDEX linked_account_puuid ON linked_account (puuid);
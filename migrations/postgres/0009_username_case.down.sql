@@ -0,0 +1,3 @@
+DROP INDEX account_username_lower;
+
+ALTER TABLE account ADD UNIQUE (username);
@@ -0,0 +1,5 @@
+-- usernames are unique ignoring case as they are on mysql and sqlite, which need no migration
+-- this fails while two usernames differ only in case, rename one of them first
+ALTER TABLE account DROP CONSTRAINT account_username_key;
+
+CREATE UNIQUE INDEX account_username_lower ON account (lower(username));
@@ -0,0 +1 @@
+DROP INDEX team_join_request_pending;
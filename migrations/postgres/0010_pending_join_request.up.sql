@@ -0,0 +1,6 @@
+-- a player keeps one pending request per team, older duplicates are rejected
+UPDATE team_join_request SET status = 'rejected', decidedAt = createdAt WHERE status = 'pending' AND id NOT IN (
+	SELECT id FROM (SELECT MAX(id) AS id FROM team_join_request WHERE status = 'pending' GROUP BY teamId, userId) AS newest
+);
+
+CREATE UNIQUE INDEX team_join_request_pending ON team_join_request (teamId, userId) WHERE status = 'pending';
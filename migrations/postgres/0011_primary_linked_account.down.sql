@@ -0,0 +1 @@
+DROP INDEX linked_account_primary;
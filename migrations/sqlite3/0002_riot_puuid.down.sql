@@ -0,0 +1,16 @@
+-- sqlite 3.34 can't drop columns so the table is rebuilt
+
+CREATE TABLE account_old (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	username VARCHAR(64) NOT NULL UNIQUE COLLATE NOCASE,
+	password VARCHAR(255) NOT NULL,
+	email VARCHAR(255) NOT NULL DEFAULT '',
+	summonerId INT NOT NULL DEFAULT 0
+);
+
+INSERT INTO account_old (id, username, password, email, summonerId)
+	SELECT id, username, password, email, legacySummonerId FROM account;
+
+DROP TABLE account;
+
+ALTER TABLE account_old RENAME TO account;
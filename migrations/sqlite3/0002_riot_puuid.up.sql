@@ -0,0 +1,11 @@
+-- summoner/v3 IDs can't be converted to encrypted IDs, they are kept in legacySummonerId
+-- and those accounts link again through the Riot ID endpoints
+ALTER TABLE account RENAME COLUMN summonerId TO legacySummonerId;
+
+ALTER TABLE account ADD COLUMN puuid VARCHAR(78) NOT NULL DEFAULT '';
+
+ALTER TABLE account ADD COLUMN summonerId VARCHAR(63) NOT NULL DEFAULT '';
+
+ALTER TABLE account ADD COLUMN region VARCHAR(8) NOT NULL DEFAULT '';
+
+CREATE INDEX account_puuid ON account (puuid);
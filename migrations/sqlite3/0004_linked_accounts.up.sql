@@ -0,0 +1,39 @@
+-- accounts linked before this migration become the primary account of their region,
+-- they were verified at signup or through the summoner endpoints but the time wasn't kept
+CREATE TABLE linked_account (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	userId BIGINT NOT NULL,
+	puuid VARCHAR(78) NOT NULL,
+	summonerId VARCHAR(63) NOT NULL,
+	region VARCHAR(8) NOT NULL,
+	riotId VARCHAR(64) NOT NULL DEFAULT '',
+	isPrimary BOOLEAN NOT NULL DEFAULT FALSE,
+	verifiedAt BIGINT NOT NULL,
+	createdAt BIGINT NOT NULL,
+	UNIQUE (region, puuid),
+	FOREIGN KEY (userId) REFERENCES account(id)
+);
+
+CREATE INDEX linked_account_user ON linked_account (userId, region);
+
+CREATE INDEX linked_account_puuid ON linked_account (puuid);
+
+INSERT INTO linked_account (userId, puuid, summonerId, region, isPrimary, verifiedAt, createdAt)
+	SELECT id, puuid, summonerId, region, TRUE, 0, 0 FROM account WHERE puuid <> '';
+
+-- sqlite 3.34 can't drop columns so the table is rebuilt
+
+CREATE TABLE account_new (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	username VARCHAR(64) NOT NULL UNIQUE COLLATE NOCASE,
+	password VARCHAR(255) NOT NULL,
+	email VARCHAR(255) NOT NULL DEFAULT '',
+	legacySummonerId INT NOT NULL DEFAULT 0
+);
+
+INSERT INTO account_new (id, username, password, email, legacySummonerId)
+	SELECT id, username, password, email, legacySummonerId FROM account;
+
+DROP TABLE account;
+
+ALTER TABLE account_new RENAME TO account;
@@ -0,0 +1,28 @@
+DROP TABLE rank_snapshot;
+
+-- sqlite 3.34 can't drop columns so the table is rebuilt
+
+CREATE TABLE linked_account_old (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	userId BIGINT NOT NULL,
+	puuid VARCHAR(78) NOT NULL,
+	summonerId VARCHAR(63) NOT NULL,
+	region VARCHAR(8) NOT NULL,
+	riotId VARCHAR(64) NOT NULL DEFAULT '',
+	isPrimary BOOLEAN NOT NULL DEFAULT FALSE,
+	verifiedAt BIGINT NOT NULL,
+	createdAt BIGINT NOT NULL,
+	UNIQUE (region, puuid),
+	FOREIGN KEY (userId) REFERENCES account(id)
+);
+
+INSERT INTO linked_account_old (id, userId, puuid, summonerId, region, riotId, isPrimary, verifiedAt, createdAt)
+	SELECT id, userId, puuid, summonerId, region, riotId, isPrimary, verifiedAt, createdAt FROM linked_account;
+
+DROP TABLE linked_account;
+
+ALTER TABLE linked_account_old RENAME TO linked_account;
+
+CREATE INDEX linked_account_user ON linked_account (userId, region);
+
+CREATE INDEX linked_account_puuid ON linked_account (puuid);
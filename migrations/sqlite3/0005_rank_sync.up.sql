@@ -0,0 +1,23 @@
+-- syncedAt 0 marks accounts never synced, the rank sync picks them up first
+ALTER TABLE linked_account ADD COLUMN profileIconId INT NOT NULL DEFAULT 0;
+
+ALTER TABLE linked_account ADD COLUMN summonerLevel BIGINT NOT NULL DEFAULT 0;
+
+ALTER TABLE linked_account ADD COLUMN syncedAt BIGINT NOT NULL DEFAULT 0;
+
+CREATE INDEX linked_account_synced ON linked_account (syncedAt);
+
+CREATE TABLE rank_snapshot (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	accountId BIGINT NOT NULL,
+	queue VARCHAR(32) NOT NULL,
+	tier VARCHAR(16) NOT NULL,
+	division VARCHAR(4) NOT NULL DEFAULT '',
+	leaguePoints INT NOT NULL DEFAULT 0,
+	wins INT NOT NULL DEFAULT 0,
+	losses INT NOT NULL DEFAULT 0,
+	takenAt BIGINT NOT NULL,
+	FOREIGN KEY (accountId) REFERENCES linked_account(id)
+);
+
+CREATE INDEX rank_snapshot_account ON rank_snapshot (accountId, queue);
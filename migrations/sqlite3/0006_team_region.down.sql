@@ -0,0 +1,19 @@
+-- sqlite 3.34 can't drop columns so the table is rebuilt
+
+CREATE TABLE team_old (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	name VARCHAR(64) NOT NULL,
+	captain BIGINT NOT NULL,
+	acceptsRequests BOOLEAN NOT NULL DEFAULT FALSE,
+	disbandedAt BIGINT NULL,
+	FOREIGN KEY (captain) REFERENCES account(id)
+);
+
+INSERT INTO team_old (id, name, captain, acceptsRequests, disbandedAt)
+	SELECT id, name, captain, acceptsRequests, disbandedAt FROM team;
+
+DROP TABLE team;
+
+ALTER TABLE team_old RENAME TO team;
+
+CREATE INDEX team_name ON team (name);
@@ -0,0 +1,32 @@
+-- a puuid identifies one riot account on every region, so it can only be linked once
+-- this fails while a puuid is linked on more than one region, unlink the extra accounts first
+-- sqlite 3.34 can't drop table constraints so the table is rebuilt
+
+CREATE TABLE linked_account_new (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	userId BIGINT NOT NULL,
+	puuid VARCHAR(78) NOT NULL,
+	summonerId VARCHAR(63) NOT NULL,
+	region VARCHAR(8) NOT NULL,
+	riotId VARCHAR(64) NOT NULL DEFAULT '',
+	isPrimary BOOLEAN NOT NULL DEFAULT FALSE,
+	verifiedAt BIGINT NOT NULL,
+	createdAt BIGINT NOT NULL,
+	profileIconId INT NOT NULL DEFAULT 0,
+	summonerLevel BIGINT NOT NULL DEFAULT 0,
+	syncedAt BIGINT NOT NULL DEFAULT 0,
+	FOREIGN KEY (userId) REFERENCES account(id)
+);
+
+INSERT INTO linked_account_new (id, userId, puuid, summonerId, region, riotId, isPrimary, verifiedAt, createdAt, profileIconId, summonerLevel, syncedAt)
+	SELECT id, userId, puuid, summonerId, region, riotId, isPrimary, verifiedAt, createdAt, profileIconId, summonerLevel, syncedAt FROM linked_account;
+
+DROP TABLE linked_account;
+
+ALTER TABLE linked_account_new RENAME TO linked_account;
+
+CREATE INDEX linked_account_user ON linked_account (userId, region);
+
+CREATE INDEX linked_account_synced ON linked_account (syncedAt);
+
+CREATE UNIQUE INDEX linked_account_puuid ON linked_account (puuid);
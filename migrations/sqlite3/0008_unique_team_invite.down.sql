@@ -0,0 +1 @@
+DROP INDEX team_invite_pair;
@@ -0,0 +1,6 @@
+-- a user keeps one primary account per region, the oldest of any duplicates stays primary
+UPDATE linked_account SET isPrimary = FALSE WHERE isPrimary = TRUE AND id NOT IN (
+	SELECT id FROM (SELECT MIN(id) AS id FROM linked_account WHERE isPrimary = TRUE GROUP BY userId, region) AS oldest
+);
+
+CREATE UNIQUE INDEX linked_account_primary ON linked_account (userId, region) WHERE isPrimary = TRUE;
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"errors"
+	"strconv"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+// mysqlDialect is used by SQLStores opened with the mysql driver
+// the default collation compares usernames ignoring case
+// schema changes commit as they run, so a migration that fails part way must be
+// reverted by hand, keep each mysql migration to as few statements as possible
+var mysqlDialect = &dialect{
+	driver:     "mysql",
+	startsWith: "LIKE CONCAT(?, '%')",
+	usernameIs: "username = ?",
+	forUpdate:  " FOR UPDATE",
+	classify:   classifyMySQLError,
+}
+
+// classifyMySQLError converts a *mysql.MySQLError to a *DBError
+// 1062 is a duplicate key, 1048 a null column, 1406 data too long, 1451 and 1452 foreign keys,
+// 1040 too many connections, 1205 a lock wait timeout and 1213 a deadlock
+func classifyMySQLError(err error) *DBError {
+	if errors.Is(err, mysql.ErrInvalidConn) {
+		return &DBError{Kind: KindUnavailable, Message: err.Error(), Err: err}
+	}
+	var e *mysql.MySQLError
+	if !errors.As(err, &e) {
+		return nil
+	}
+	kind := KindInternal
+	switch e.Number {
+	case 1062:
+		kind = KindConflict
+	case 1048, 1406, 1451, 1452:
+		kind = KindValidation
+	case 1040, 1205, 1213:
+		kind = KindUnavailable
+	}
+	return &DBError{Kind: kind, Code: strconv.Itoa(int(e.Number)), Message: e.Message, Err: e}
+}
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"bufio"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// PasswordPolicy holds the rules a new password must satisfy
+type PasswordPolicy struct {
+	MinLength int
+	MaxLength int
+	Cost      int
+	Blocklist map[string]struct{}
+}
+
+// NewPasswordPolicy creates the default policy, main() adjusts it from the environment
+// MaxLength is capped by bcrypt which ignores bytes past 72
+func NewPasswordPolicy() *PasswordPolicy {
+	return &PasswordPolicy{
+		MinLength: 8,
+		MaxLength: 72,
+		Cost:      bcrypt.DefaultCost,
+		Blocklist: map[string]struct{}{},
+	}
+}
+
+// Check returns an error if password does not satisfy the policy
+func (p *PasswordPolicy) Check(password string) error {
+	if len(password) < p.MinLength {
+		return fmt.Errorf("password must be at least %d characters", p.MinLength)
+	}
+	if len(password) > p.MaxLength {
+		return fmt.Errorf("password must be at most %d characters", p.MaxLength)
+	}
+	if _, ok := p.Blocklist[strings.ToLower(password)]; ok {
+		return errors.New("password is too common")
+	}
+	return nil
+}
+
+// LoadBlocklist reads a file of breached passwords, one per line
+func (p *PasswordPolicy) LoadBlocklist(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		p.Blocklist[strings.ToLower(line)] = struct{}{}
+	}
+	return scanner.Err()
+}
+
+// Hash hashes password with the cost of the policy
+func (p *PasswordPolicy) Hash(password string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), p.Cost)
+	if err != nil {
+		return "", err
+	}
+	return string(hash), nil
+}
+
+// isPasswordHash reports whether a stored password is a bcrypt hash
+// rather than a legacy plaintext value
+func isPasswordHash(stored string) bool {
+	_, err := bcrypt.Cost([]byte(stored))
+	return err == nil
+}
+
+// Verify compares password against a stored value and reports
+// whether the stored value should be rehashed with the cost of the policy
+func (p *PasswordPolicy) Verify(stored, password string) (ok bool, rehash bool) {
+	if !isPasswordHash(stored) {
+		return subtle.ConstantTimeCompare([]byte(stored), []byte(password)) == 1, true
+	}
+	if bcrypt.CompareHashAndPassword([]byte(stored), []byte(password)) != nil {
+		return false, false
+	}
+	cost, _ := bcrypt.Cost([]byte(stored))
+	return true, cost != p.Cost
+}
+
+// SetPassword replaces the stored password hash of a user
+func (m *SQLStore) SetPassword(userID int64, hash string) error {
+	stmt, err := m.db.Prepare("UPDATE account SET password=? WHERE id=?")
+	if err != nil {
+		return err
+	}
+	_, err = stmt.Exec(hash, userID)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// UpgradePasswords hashes every account password still stored as plaintext with the cost of policy
+func (m *SQLStore) UpgradePasswords(policy *PasswordPolicy) (int, error) {
+	type account struct {
+		id       int64
+		password string
+	}
+	var plain []account
+	rows, err := m.db.Query("SELECT id, password FROM account")
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var a account
+		err := rows.Scan(&a.id, &a.password)
+		if err != nil {
+			return 0, err
+		}
+		if !isPasswordHash(a.password) {
+			plain = append(plain, a)
+		}
+	}
+	err = rows.Err()
+	if err != nil {
+		return 0, err
+	}
+	for i, a := range plain {
+		hash, err := policy.Hash(a.password)
+		if err != nil {
+			return i, err
+		}
+		err = m.SetPassword(a.id, hash)
+		if err != nil {
+			return i, err
+		}
+	}
+	return len(plain), nil
+}
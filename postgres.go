@@ -0,0 +1,43 @@
+package main
+
+import (
+	"errors"
+
+	"github.com/lib/pq"
+)
+
+// postgresDialect is used by SQLStores opened with the postgres driver
+// ILIKE keeps prefix searches case insensitive as they are on MySQL and SQLite,
+// and usernames are unique and looked up by lower(username) for the same reason
+var postgresDialect = &dialect{
+	driver:           "postgres",
+	startsWith:       "ILIKE ? || '%'",
+	usernameIs:       "lower(username) = lower(?)",
+	forUpdate:        " FOR UPDATE",
+	numberedParams:   true,
+	returningID:      true,
+	classify:         classifyPostgresError,
+	transactionalDDL: true,
+}
+
+// classifyPostgresError converts a *pq.Error to a *DBError using its SQLSTATE
+// class 08 covers connection failures
+func classifyPostgresError(err error) *DBError {
+	var e *pq.Error
+	if !errors.As(err, &e) {
+		return nil
+	}
+	kind := KindInternal
+	switch e.Code.Name() {
+	case "unique_violation":
+		kind = KindConflict
+	case "foreign_key_violation", "not_null_violation", "check_violation", "string_data_right_truncation":
+		kind = KindValidation
+	case "query_canceled", "lock_not_available", "deadlock_detected", "too_many_connections":
+		kind = KindUnavailable
+	}
+	if e.Code.Class() == "08" {
+		kind = KindUnavailable
+	}
+	return &DBError{Kind: kind, Code: string(e.Code), Message: e.Message, Err: e}
+}
@@ -0,0 +1,272 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/anthonyrouseau/lss-api/riot"
+	"github.com/go-chi/render"
+)
+
+// rankTiers are the league tiers from lowest to highest, the apex tiers from
+// master up have no divisions and league points that keep counting
+var rankTiers = []string{"IRON", "BRONZE", "SILVER", "GOLD", "PLATINUM", "EMERALD", "DIAMOND", "MASTER", "GRANDMASTER", "CHALLENGER"}
+
+// rankDivisions are the divisions of a tier from lowest to highest
+var rankDivisions = []string{"IV", "III", "II", "I"}
+
+// tierUnranked is recorded when an account drops out of a queue, such as after a season reset
+const tierUnranked = "UNRANKED"
+
+// rankedQueues are the queues synced from riot and kept in rank history
+var rankedQueues = []string{riot.QueueSolo, riot.QueueFlex}
+
+// Rank is the standing of a linked account in one ranked queue
+type Rank struct {
+	Queue        string `json:"queue"`
+	Tier         string `json:"tier"`
+	Division     string `json:"division,omitempty"`
+	LeaguePoints int    `json:"leaguePoints"`
+	Wins         int    `json:"wins"`
+	Losses       int    `json:"losses"`
+}
+
+// RankSnapshot is the rank of a linked account as it was at TakenAt
+// a snapshot is only taken when the rank changes
+type RankSnapshot struct {
+	ID        int64  `json:"id"`
+	AccountID int64  `json:"accountId"`
+	UserID    int64  `json:"-"`
+	Region    string `json:"region"`
+	Rank
+	TakenAt int64 `json:"takenAt"`
+}
+
+// TeamRank is the average solo queue rank of the ranked players on a roster
+type TeamRank struct {
+	Tier          string `json:"tier"`
+	Division      string `json:"division,omitempty"`
+	LeaguePoints  int    `json:"leaguePoints"`
+	RankedMembers int    `json:"rankedMembers"`
+}
+
+// apexScore is the score of master 0 LP, every tier below it is worth 400
+var apexScore = indexOf(rankTiers, "MASTER") * 400
+
+func indexOf(list []string, value string) int {
+	for i, v := range list {
+		if v == value {
+			return i
+		}
+	}
+	return -1
+}
+
+// score places a rank on a single scale of league points so ranks can be
+// compared and averaged, it is -1 for unranked or unknown tiers
+func (r *Rank) score() int {
+	tier := indexOf(rankTiers, r.Tier)
+	if tier < 0 {
+		return -1
+	}
+	if tier*400 >= apexScore {
+		return apexScore + r.LeaguePoints
+	}
+	division := indexOf(rankDivisions, r.Division)
+	if division < 0 {
+		division = 0
+	}
+	return tier*400 + division*100 + r.LeaguePoints
+}
+
+// rankFromEntry converts a league-v4 entry to a rank
+func rankFromEntry(entry *riot.LeagueEntry) *Rank {
+	r := &Rank{
+		Queue:        entry.QueueType,
+		Tier:         strings.ToUpper(entry.Tier),
+		Division:     entry.Rank,
+		LeaguePoints: entry.LeaguePoints,
+		Wins:         entry.Wins,
+		Losses:       entry.Losses,
+	}
+	if indexOf(rankTiers, r.Tier)*400 >= apexScore {
+		r.Division = ""
+	}
+	return r
+}
+
+// bestSoloRanks returns the highest solo queue rank among the snapshots of each user
+func bestSoloRanks(snapshots []*RankSnapshot) map[int64]*Rank {
+	best := map[int64]*Rank{}
+	for _, s := range snapshots {
+		if s.Queue != riot.QueueSolo || s.score() < 0 {
+			continue
+		}
+		if current, ok := best[s.UserID]; !ok || s.score() > current.score() {
+			rank := s.Rank
+			best[s.UserID] = &rank
+		}
+	}
+	return best
+}
+
+// averageRank averages ranks as league points, nil if none are ranked
+// an average above master is reported as master with the extra league points
+func averageRank(ranks map[int64]*Rank) *TeamRank {
+	total, n := 0, 0
+	for _, r := range ranks {
+		if score := r.score(); score >= 0 {
+			total += score
+			n++
+		}
+	}
+	if n == 0 {
+		return nil
+	}
+	score := total / n
+	if score >= apexScore {
+		return &TeamRank{Tier: "MASTER", LeaguePoints: score - apexScore, RankedMembers: n}
+	}
+	return &TeamRank{
+		Tier:          rankTiers[score/400],
+		Division:      rankDivisions[score%400/100],
+		LeaguePoints:  score % 100,
+		RankedMembers: n,
+	}
+}
+
+// RankSnapshotResponse represents a rank snapshot sent to the client
+type RankSnapshotResponse struct {
+	*RankSnapshot
+}
+
+// NewRankSnapshotResponse creates a response from a rank snapshot
+func NewRankSnapshotResponse(s *RankSnapshot) *RankSnapshotResponse {
+	return &RankSnapshotResponse{RankSnapshot: s}
+}
+
+// Render allows for preprocessing of responses
+func (sr *RankSnapshotResponse) Render(w http.ResponseWriter, r *http.Request) error {
+	return nil
+}
+
+// NewRankSnapshotListResponse creates a list of rank snapshot responses
+func NewRankSnapshotListResponse(snapshots []*RankSnapshot) []render.Renderer {
+	list := []render.Renderer{}
+	for _, s := range snapshots {
+		list = append(list, NewRankSnapshotResponse(s))
+	}
+	return list
+}
+
+const rankSnapshotColumns = "s.id, s.accountId, a.userId, a.region, s.queue, s.tier, s.division, s.leaguePoints, s.wins, s.losses, s.takenAt"
+
+func scanRankSnapshot(row interface{ Scan(...interface{}) error }) (*RankSnapshot, error) {
+	var s RankSnapshot
+	err := row.Scan(&s.ID, &s.AccountID, &s.UserID, &s.Region, &s.Queue, &s.Tier, &s.Division, &s.LeaguePoints, &s.Wins, &s.Losses, &s.TakenAt)
+	if err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+// queryRankSnapshots runs a query selecting rankSnapshotColumns
+func (m *SQLStore) queryRankSnapshots(query string, args ...interface{}) ([]*RankSnapshot, error) {
+	var snapshots []*RankSnapshot
+	rows, err := m.db.Query(query, args...)
+	if err != nil {
+		return snapshots, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		s, err := scanRankSnapshot(rows)
+		if err != nil {
+			return snapshots, err
+		}
+		snapshots = append(snapshots, s)
+	}
+	err = rows.Err()
+	if err != nil {
+		return snapshots, err
+	}
+	return snapshots, nil
+}
+
+// currentRanks returns the latest ranked snapshot of each queue of the linked accounts matching where
+func (m *SQLStore) currentRanks(where string, args ...interface{}) ([]*RankSnapshot, error) {
+	query := "SELECT " + rankSnapshotColumns + ` FROM rank_snapshot s INNER JOIN linked_account a ON a.id = s.accountId
+		WHERE ` + where + ` AND s.tier <> ?
+		AND s.id = (SELECT MAX(id) FROM rank_snapshot WHERE accountId = s.accountId AND queue = s.queue)
+		ORDER BY s.accountId, s.queue`
+	return m.queryRankSnapshots(query, append(args, tierUnranked)...)
+}
+
+// inIDs returns the placeholders of an IN list of ids and the ids as query arguments
+func inIDs(ids []int64) (string, []interface{}) {
+	args := make([]interface{}, 0, len(ids))
+	for _, id := range ids {
+		args = append(args, id)
+	}
+	return strings.TrimSuffix(strings.Repeat("?,", len(ids)), ","), args
+}
+
+// GetCurrentRanks returns the current ranks of the primary accounts of userIDs
+func (m *SQLStore) GetCurrentRanks(userIDs []int64) ([]*RankSnapshot, error) {
+	if len(userIDs) == 0 {
+		return nil, nil
+	}
+	placeholders, args := inIDs(userIDs)
+	return m.currentRanks("a.userId IN ("+placeholders+") AND a.isPrimary = ?", append(args, true)...)
+}
+
+// GetRosterRanks returns the current ranks of the primary accounts the players on each of teamIDs
+// have on the team's region by team, in one query however many teams there are
+func (m *SQLStore) GetRosterRanks(teamIDs []int64) (map[int64][]*RankSnapshot, error) {
+	ranks := map[int64][]*RankSnapshot{}
+	if len(teamIDs) == 0 {
+		return ranks, nil
+	}
+	placeholders, args := inIDs(teamIDs)
+	query := "SELECT roster.teamID, " + rankSnapshotColumns + ` FROM rank_snapshot s INNER JOIN linked_account a ON a.id = s.accountId
+		INNER JOIN roster ON roster.userID = a.userId
+		INNER JOIN team ON team.id = roster.teamID
+		WHERE roster.teamID IN (` + placeholders + `) AND a.region = team.region AND a.isPrimary = ? AND s.tier <> ?
+		AND s.id = (SELECT MAX(id) FROM rank_snapshot WHERE accountId = s.accountId AND queue = s.queue)
+		ORDER BY roster.teamID, s.accountId, s.queue`
+	rows, err := m.db.Query(query, append(args, true, tierUnranked)...)
+	if err != nil {
+		return ranks, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var teamID int64
+		var s RankSnapshot
+		err := rows.Scan(&teamID, &s.ID, &s.AccountID, &s.UserID, &s.Region, &s.Queue, &s.Tier, &s.Division, &s.LeaguePoints, &s.Wins, &s.Losses, &s.TakenAt)
+		if err != nil {
+			return ranks, err
+		}
+		ranks[teamID] = append(ranks[teamID], &s)
+	}
+	err = rows.Err()
+	if err != nil {
+		return ranks, err
+	}
+	return ranks, nil
+}
+
+// GetRankHistory returns up to limit rank snapshots of the accounts linked to userID, newest first
+// an empty region or queue matches any
+func (m *SQLStore) GetRankHistory(userID int64, region, queue string, limit int) ([]*RankSnapshot, error) {
+	query := "SELECT " + rankSnapshotColumns + " FROM rank_snapshot s INNER JOIN linked_account a ON a.id = s.accountId WHERE a.userId = ?"
+	args := []interface{}{userID}
+	if region != "" {
+		query += " AND a.region = ?"
+		args = append(args, region)
+	}
+	if queue != "" {
+		query += " AND s.queue = ?"
+		args = append(args, queue)
+	}
+	query += " ORDER BY s.takenAt DESC, s.id DESC LIMIT ?"
+	return m.queryRankSnapshots(query, append(args, limit)...)
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/anthonyrouseau/lss-api/riot"
+	"github.com/go-chi/chi"
+	"github.com/go-chi/render"
+)
+
+// rank history page sizes
+const (
+	defaultRankHistoryLimit = 50
+	maxRankHistoryLimit     = 200
+)
+
+// GetRankHistory renders the rank snapshots of a user newest first
+// the region, queue and limit query params narrow the history
+func (s *Server) GetRankHistory(w http.ResponseWriter, r *http.Request) {
+	userID, err := strconv.ParseInt(chi.URLParam(r, "userID"), 10, 64)
+	if err != nil {
+		render.Render(w, r, ErrBadRequest(errors.New("user id not valid")))
+		return
+	}
+	query := r.URL.Query()
+	v := &ValidationError{}
+	region := query.Get("region")
+	if region != "" {
+		platform, err := riot.ParsePlatform(region)
+		if err != nil {
+			v.Add("region", "is not a riot platform")
+		}
+		region = string(platform)
+	}
+	queue := query.Get("queue")
+	if queue != "" && indexOf(rankedQueues, queue) < 0 {
+		v.Add("queue", fmt.Sprintf("must be %s or %s", riot.QueueSolo, riot.QueueFlex))
+	}
+	limit := defaultRankHistoryLimit
+	if l := query.Get("limit"); l != "" {
+		limit, err = strconv.Atoi(l)
+		if err != nil || limit < 1 || limit > maxRankHistoryLimit {
+			v.Add("limit", fmt.Sprintf("must be between 1 and %d", maxRankHistoryLimit))
+		}
+	}
+	if err := v.Err(); err != nil {
+		render.Render(w, r, ErrBadRequest(err))
+		return
+	}
+	history, err := s.users.GetRankHistory(userID, region, queue, limit)
+	if err != nil {
+		render.Render(w, r, ErrDB(err))
+		return
+	}
+	if err := render.RenderList(w, r, NewRankSnapshotListResponse(history)); err != nil {
+		render.Render(w, r, ErrRender(err))
+		return
+	}
+}
+
+// attachUserRanks sets the Rank of each user from their primary accounts
+func (s *Server) attachUserRanks(users []*User) error {
+	ids := make([]int64, 0, len(users))
+	for _, user := range users {
+		ids = append(ids, user.ID)
+	}
+	snapshots, err := s.users.GetCurrentRanks(ids)
+	if err != nil {
+		return err
+	}
+	ranks := bestSoloRanks(snapshots)
+	for _, user := range users {
+		user.Rank = ranks[user.ID]
+	}
+	return nil
+}
+
+// attachAverageRanks sets the AverageRank of each team from its roster
+func (s *Server) attachAverageRanks(teams []*Team) error {
+	if len(teams) == 0 {
+		return nil
+	}
+	teamIDs := make([]int64, 0, len(teams))
+	for _, team := range teams {
+		teamIDs = append(teamIDs, team.ID)
+	}
+	ranks, err := s.teams.GetRosterRanks(teamIDs)
+	if err != nil {
+		return err
+	}
+	for _, team := range teams {
+		team.AverageRank = averageRank(bestSoloRanks(ranks[team.ID]))
+	}
+	return nil
+}
@@ -0,0 +1,188 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/anthonyrouseau/lss-api/riot"
+)
+
+// rank sync defaults, overridden by the ranksyncinterval and rankstaleafter env variables
+// rankSyncBatch is the most accounts refreshed per interval
+const (
+	defaultRankSyncInterval = time.Minute
+	defaultRankStaleAfter   = 6 * time.Hour
+	rankSyncBatch           = 50
+)
+
+// AccountSync is the profile and ranks of a linked account as fetched from riot
+// Ranks nil leaves the rank history untouched, queues missing from it are unranked
+type AccountSync struct {
+	AccountID     int64
+	SummonerID    string
+	ProfileIconID int
+	SummonerLevel int64
+	Ranks         []*Rank
+	SyncedAt      int64
+}
+
+// syncRanks refreshes the linked accounts not synced for staleAfter every interval until stop is closed
+func (s *Server) syncRanks(interval, staleAfter time.Duration, stop <-chan struct{}) {
+	ctx := context.Background()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case now := <-ticker.C:
+			s.syncStaleAccounts(ctx, now.Add(-staleAfter), stop)
+		case <-stop:
+			return
+		}
+	}
+}
+
+// syncStaleAccounts syncs a batch of the accounts least recently synced before before
+// accounts are synced one at a time so the riot client can pace them, and the batch
+// stops early when riot is rate limiting or down, the rest wait for the next tick
+func (s *Server) syncStaleAccounts(ctx context.Context, before time.Time, stop <-chan struct{}) {
+	accounts, err := s.users.GetStaleAccounts(before.Unix(), rankSyncBatch)
+	if err != nil {
+		log.Printf("rank sync: %v", err)
+		return
+	}
+	for _, account := range accounts {
+		select {
+		case <-stop:
+			return
+		default:
+		}
+		err := s.syncAccount(ctx, account)
+		if errors.Is(err, riot.ErrRateLimited) || errors.Is(err, riot.ErrUnavailable) {
+			log.Printf("rank sync: stopping batch: %v", err)
+			return
+		}
+		if err != nil && !errors.Is(err, errLinkedAccountNotFound) {
+			log.Printf("rank sync: account %d: %v", account.ID, err)
+		}
+	}
+}
+
+// syncAccount fetches the summoner and league entries of a linked account and saves them
+// a summoner riot no longer knows keeps its profile and ranks until a later sync finds it
+func (s *Server) syncAccount(ctx context.Context, account *LinkedAccount) error {
+	platform := riot.Platform(account.Region)
+	sync := &AccountSync{
+		AccountID:     account.ID,
+		SummonerID:    account.SummonerID,
+		ProfileIconID: account.ProfileIconID,
+		SummonerLevel: account.SummonerLevel,
+		SyncedAt:      time.Now().Unix(),
+	}
+	summoner, err := s.riot.SummonerByPUUID(ctx, platform, account.PUUID)
+	if errors.Is(err, riot.ErrNotFound) {
+		return s.users.SaveAccountSync(sync)
+	}
+	if err != nil {
+		return err
+	}
+	entries, err := s.riot.LeagueEntries(ctx, platform, summoner.ID)
+	if err != nil && !errors.Is(err, riot.ErrNotFound) {
+		return err
+	}
+	sync.SummonerID = summoner.ID
+	sync.ProfileIconID = summoner.ProfileIconID
+	sync.SummonerLevel = summoner.SummonerLevel
+	if err == nil {
+		sync.Ranks = []*Rank{}
+		for _, entry := range entries {
+			if indexOf(rankedQueues, entry.QueueType) >= 0 {
+				sync.Ranks = append(sync.Ranks, rankFromEntry(entry))
+			}
+		}
+	}
+	return s.users.SaveAccountSync(sync)
+}
+
+// GetStaleAccounts returns up to limit linked accounts last synced before before, least recent first
+func (m *SQLStore) GetStaleAccounts(before int64, limit int) ([]*LinkedAccount, error) {
+	var accounts []*LinkedAccount
+	rows, err := m.db.Query("SELECT "+linkedAccountColumns+" FROM linked_account WHERE syncedAt < ? ORDER BY syncedAt, id LIMIT ?", before, limit)
+	if err != nil {
+		return accounts, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		account, err := scanLinkedAccount(rows)
+		if err != nil {
+			return accounts, err
+		}
+		accounts = append(accounts, account)
+	}
+	err = rows.Err()
+	if err != nil {
+		return accounts, err
+	}
+	return accounts, nil
+}
+
+// SaveAccountSync stores the profile of a linked account and snapshots each ranked
+// queue whose rank changed since its last snapshot
+func (m *SQLStore) SaveAccountSync(sync *AccountSync) error {
+	return m.withTx("save account sync", func(tx *sqlTx) error {
+		var n int
+		err := tx.QueryRow("SELECT COUNT(*) FROM linked_account WHERE id=?", sync.AccountID).Scan(&n)
+		if err != nil {
+			return err
+		}
+		if n == 0 {
+			return errLinkedAccountNotFound
+		}
+		_, err = tx.Exec("UPDATE linked_account SET summonerId=?, profileIconId=?, summonerLevel=?, syncedAt=? WHERE id=?",
+			sync.SummonerID, sync.ProfileIconID, sync.SummonerLevel, sync.SyncedAt, sync.AccountID)
+		if err != nil {
+			return err
+		}
+		if sync.Ranks == nil {
+			return nil
+		}
+		for _, rank := range syncedRanks(sync) {
+			var last Rank
+			err := tx.QueryRow("SELECT queue, tier, division, leaguePoints, wins, losses FROM rank_snapshot WHERE accountId=? AND queue=? ORDER BY id DESC LIMIT 1", sync.AccountID, rank.Queue).
+				Scan(&last.Queue, &last.Tier, &last.Division, &last.LeaguePoints, &last.Wins, &last.Losses)
+			switch {
+			case err == sql.ErrNoRows:
+				if rank.Tier == tierUnranked {
+					continue
+				}
+			case err != nil:
+				return err
+			case last == *rank:
+				continue
+			}
+			_, err = tx.Exec("INSERT INTO rank_snapshot(accountId,queue,tier,division,leaguePoints,wins,losses,takenAt) VALUES(?,?,?,?,?,?,?,?)",
+				sync.AccountID, rank.Queue, rank.Tier, rank.Division, rank.LeaguePoints, rank.Wins, rank.Losses, sync.SyncedAt)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// syncedRanks returns the rank of every ranked queue in a sync, unranked where it has none
+func syncedRanks(sync *AccountSync) []*Rank {
+	var ranks []*Rank
+	for _, queue := range rankedQueues {
+		rank := &Rank{Queue: queue, Tier: tierUnranked}
+		for _, r := range sync.Ranks {
+			if r.Queue == queue {
+				rank = r
+			}
+		}
+		ranks = append(ranks, rank)
+	}
+	return ranks
+}
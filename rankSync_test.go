@@ -0,0 +1,59 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/anthonyrouseau/lss-api/riot"
+)
+
+func TestSyncStaleAccounts(t *testing.T) {
+	store := NewMemoryStore()
+	stub := riot.NewStub()
+	s := NewServer(store, store, store, stub, []byte("test secret"), NewRules())
+	user := newTestUser(t, store, "player")
+	ranked := linkTestAccount(t, store, user, "ranked", "na1")
+	gone := linkTestAccount(t, store, user, "gone", "na1")
+	stub.AddSummoner(riot.NA1, &riot.Account{PUUID: "ranked", GameName: "Ranked", TagLine: "NA1"},
+		&riot.Summoner{ID: "summoner-new", ProfileIconID: 12, SummonerLevel: 150}, "")
+	stub.SetLeagueEntries(riot.NA1, "summoner-new", []*riot.LeagueEntry{
+		{QueueType: riot.QueueSolo, Tier: "gold", Rank: "I", LeaguePoints: 75},
+		{QueueType: "CHERRY", Tier: "GOLD", Rank: "I"},
+	})
+	stop := make(chan struct{})
+	ctx := context.Background()
+
+	// a rate limited riot stops the batch and leaves the accounts stale
+	stub.Err = riot.ErrRateLimited
+	s.syncStaleAccounts(ctx, time.Now().Add(time.Second), stop)
+	if stale, err := store.GetStaleAccounts(time.Now().Add(time.Second).Unix(), rankSyncBatch); err != nil || len(stale) != 2 {
+		t.Fatalf("stale accounts after a rate limited sync = %+v, %v", stale, err)
+	}
+
+	stub.Err = nil
+	s.syncStaleAccounts(ctx, time.Now().Add(time.Second), stop)
+	if stale, err := store.GetStaleAccounts(time.Now().Add(-time.Minute).Unix(), rankSyncBatch); err != nil || len(stale) != 0 {
+		t.Errorf("stale accounts after a sync = %+v, %v", stale, err)
+	}
+	accounts, err := store.GetLinkedAccounts(user)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, a := range accounts {
+		switch a.ID {
+		case ranked.ID:
+			if a.SummonerID != "summoner-new" || a.ProfileIconID != 12 || a.SummonerLevel != 150 || a.SyncedAt == 0 {
+				t.Errorf("synced profile = %+v", a)
+			}
+			if len(a.Ranks) != 1 || a.Ranks[0].Tier != "GOLD" || a.Ranks[0].Division != "I" || a.Ranks[0].LeaguePoints != 75 {
+				t.Errorf("synced ranks = %+v, want solo queue gold I only", a.Ranks)
+			}
+		case gone.ID:
+			// riot doesn't know the summoner, it keeps its profile until a later sync finds it
+			if a.SummonerID != gone.SummonerID || a.SyncedAt == 0 || len(a.Ranks) != 0 {
+				t.Errorf("account unknown to riot = %+v", a)
+			}
+		}
+	}
+}
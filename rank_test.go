@@ -0,0 +1,152 @@
+package main
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/anthonyrouseau/lss-api/riot"
+)
+
+func TestAverageRank(t *testing.T) {
+	solo := func(userID int64, tier, division string, lp int) *RankSnapshot {
+		return &RankSnapshot{UserID: userID, Rank: Rank{Queue: riot.QueueSolo, Tier: tier, Division: division, LeaguePoints: lp}}
+	}
+	tests := []struct {
+		name      string
+		snapshots []*RankSnapshot
+		want      *TeamRank
+	}{
+		{"no ranks", nil, nil},
+		{"one player", []*RankSnapshot{solo(1, "GOLD", "II", 40)}, &TeamRank{Tier: "GOLD", Division: "II", LeaguePoints: 40, RankedMembers: 1}},
+		{"between tiers", []*RankSnapshot{solo(1, "GOLD", "I", 50), solo(2, "PLATINUM", "IV", 50)},
+			&TeamRank{Tier: "PLATINUM", Division: "IV", LeaguePoints: 0, RankedMembers: 2}},
+		{"best account of a player", []*RankSnapshot{solo(1, "SILVER", "IV", 0), solo(1, "DIAMOND", "IV", 0)},
+			&TeamRank{Tier: "DIAMOND", Division: "IV", RankedMembers: 1}},
+		{"flex and unknown tiers are left out", []*RankSnapshot{solo(1, "IRON", "IV", 10), {UserID: 2, Rank: Rank{Queue: riot.QueueFlex, Tier: "CHALLENGER"}},
+			solo(3, tierUnranked, "", 0)}, &TeamRank{Tier: "IRON", Division: "IV", LeaguePoints: 10, RankedMembers: 1}},
+		{"above master", []*RankSnapshot{solo(1, "CHALLENGER", "", 900), solo(2, "GRANDMASTER", "", 300)},
+			&TeamRank{Tier: "MASTER", LeaguePoints: 600, RankedMembers: 2}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := averageRank(bestSoloRanks(tt.snapshots))
+			if (got == nil) != (tt.want == nil) || got != nil && *got != *tt.want {
+				t.Errorf("average = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+// syncTestRanks saves a sync of accountID with ranks taken at takenAt
+func syncTestRanks(t *testing.T, store Store, accountID int64, takenAt int64, ranks ...*Rank) {
+	t.Helper()
+	if ranks == nil {
+		ranks = []*Rank{}
+	}
+	if err := store.SaveAccountSync(&AccountSync{AccountID: accountID, SummonerID: "summoner", Ranks: ranks, SyncedAt: takenAt}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSaveAccountSync(t *testing.T) {
+	forEachStore(t, func(t *testing.T, store Store) {
+		user := newTestUser(t, store, "player")
+		account := linkTestAccount(t, store, user, "main", "na1")
+		gold := &Rank{Queue: riot.QueueSolo, Tier: "GOLD", Division: "II", LeaguePoints: 40, Wins: 10, Losses: 8}
+		flex := &Rank{Queue: riot.QueueFlex, Tier: "SILVER", Division: "I", LeaguePoints: 5}
+
+		syncTestRanks(t, store, account.ID, 100, gold, flex)
+		syncTestRanks(t, store, account.ID, 200, gold, flex)
+		won := *gold
+		won.LeaguePoints, won.Wins = 60, 11
+		syncTestRanks(t, store, account.ID, 300, &won, flex)
+		// dropping out of solo queue, as after a season reset, is recorded as unranked
+		syncTestRanks(t, store, account.ID, 400, flex)
+
+		history, err := store.GetRankHistory(user, "", riot.QueueSolo, 10)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got []string
+		for _, s := range history {
+			got = append(got, s.Tier)
+		}
+		if len(history) != 3 || history[0].Tier != tierUnranked || history[1].LeaguePoints != 60 || history[2].TakenAt != 100 {
+			t.Errorf("solo history = %v %+v, want unranked, 60 LP and the first sync, newest first", got, history)
+		}
+		if flexHistory, err := store.GetRankHistory(user, "na1", riot.QueueFlex, 10); err != nil || len(flexHistory) != 1 {
+			t.Errorf("flex history = %+v, %v, want one snapshot of an unchanged rank", flexHistory, err)
+		}
+
+		profile := &AccountSync{AccountID: account.ID, SummonerID: "renamed", ProfileIconID: 7, SummonerLevel: 300, SyncedAt: 500}
+		if err := store.SaveAccountSync(profile); err != nil {
+			t.Fatal(err)
+		}
+		accounts, err := store.GetLinkedAccounts(user)
+		if err != nil || len(accounts) != 1 {
+			t.Fatalf("accounts = %+v, %v", accounts, err)
+		}
+		a := accounts[0]
+		if a.SummonerID != "renamed" || a.ProfileIconID != 7 || a.SummonerLevel != 300 || a.SyncedAt != 500 {
+			t.Errorf("profile after sync = %+v", a)
+		}
+		if len(a.Ranks) != 1 || a.Ranks[0].Queue != riot.QueueFlex {
+			t.Errorf("current ranks = %+v, want flex only, a sync without ranks keeps them and unranked isn't current", a.Ranks)
+		}
+		if err := store.SaveAccountSync(&AccountSync{AccountID: 999, SyncedAt: 500}); !errors.Is(err, errLinkedAccountNotFound) {
+			t.Errorf("sync of an unknown account: %v, want %v", err, errLinkedAccountNotFound)
+		}
+	})
+}
+
+func TestGetRosterRanks(t *testing.T) {
+	forEachStore(t, func(t *testing.T, store Store) {
+		captain := newTestUser(t, store, "captain")
+		player := newTestUser(t, store, "player")
+		team := newTestTeam(t, store, captain)
+		link := &TeamJoinLink{Team: team.ID}
+		if err := store.NewTeamJoinLink(link, captain); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := store.RedeemTeamJoinLink(link.Code, player); err != nil {
+			t.Fatal(err)
+		}
+		euwTeam := &Team{Name: "Euw Squad", Captain: player, Region: "euw1"}
+		if err := store.CreateTeam(euwTeam); err != nil {
+			t.Fatal(err)
+		}
+		outsider := newTestUser(t, store, "outsider")
+
+		now := time.Now().Unix()
+		solo := func(tier string) *Rank { return &Rank{Queue: riot.QueueSolo, Tier: tier, Division: "IV"} }
+		syncTestRanks(t, store, linkTestAccount(t, store, captain, "captain-na", "na1").ID, now, solo("GOLD"))
+		// a smurf on the team's region isn't primary, a main on another region isn't on the team's region
+		syncTestRanks(t, store, linkTestAccount(t, store, captain, "captain-smurf", "na1").ID, now, solo("DIAMOND"))
+		syncTestRanks(t, store, linkTestAccount(t, store, captain, "captain-kr", "kr").ID, now, solo("CHALLENGER"))
+		syncTestRanks(t, store, linkTestAccount(t, store, player, "player-na", "na1").ID, now, solo("SILVER"))
+		syncTestRanks(t, store, linkTestAccount(t, store, player, "player-euw", "euw1").ID, now, solo("PLATINUM"))
+		syncTestRanks(t, store, linkTestAccount(t, store, outsider, "outsider-na", "na1").ID, now, solo("IRON"))
+
+		ranks, err := store.GetRosterRanks([]int64{team.ID, euwTeam.ID})
+		if err != nil {
+			t.Fatal(err)
+		}
+		tiers := func(teamID int64) map[int64]string {
+			got := map[int64]string{}
+			for _, s := range ranks[teamID] {
+				got[s.UserID] = s.Tier
+			}
+			return got
+		}
+		if got := tiers(team.ID); len(got) != 2 || got[captain] != "GOLD" || got[player] != "SILVER" {
+			t.Errorf("na1 team ranks = %v, want the na1 primary accounts of its players", got)
+		}
+		if got := tiers(euwTeam.ID); len(got) != 1 || got[player] != "PLATINUM" {
+			t.Errorf("euw1 team ranks = %v, want the euw1 primary account of its player", got)
+		}
+		if avg := averageRank(bestSoloRanks(ranks[team.ID])); avg == nil || avg.Tier != "SILVER" || avg.Division != "II" {
+			t.Errorf("na1 team average = %+v, want between silver and gold", avg)
+		}
+	})
+}
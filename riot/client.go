@@ -0,0 +1,122 @@
+package riot
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// DefaultBaseURL is the Riot API, {platform} is replaced by the platform or region of each request
+const DefaultBaseURL = "https://{platform}.api.riotgames.com"
+
+// HTTPClient is a Client for the Riot API or a server that imitates it
+type HTTPClient struct {
+	baseURL string
+	apiKey  string
+	http    *http.Client
+}
+
+var _ Client = (*HTTPClient)(nil)
+
+// NewHTTPClient creates a client sending requests to baseURL through a RateLimitedTransport
+// baseURL may contain {platform}, an empty baseURL uses DefaultBaseURL
+func NewHTTPClient(baseURL, apiKey string, timeout time.Duration) *HTTPClient {
+	if baseURL == "" {
+		baseURL = DefaultBaseURL
+	}
+	return &HTTPClient{
+		baseURL: strings.TrimRight(baseURL, "/"),
+		apiKey:  apiKey,
+		http: &http.Client{
+			Timeout:   timeout,
+			Transport: NewRateLimitedTransport(http.DefaultTransport),
+		},
+	}
+}
+
+// AccountByRiotID returns the account known as id in the region of platform
+func (c *HTTPClient) AccountByRiotID(ctx context.Context, platform Platform, id RiotID) (*Account, error) {
+	account := &Account{}
+	path := "/riot/account/v1/accounts/by-riot-id/" + url.PathEscape(id.GameName) + "/" + url.PathEscape(id.TagLine)
+	err := c.get(ctx, string(platform.accountRegion()), "account-v1.by-riot-id", path, account)
+	if err != nil {
+		return nil, err
+	}
+	return account, nil
+}
+
+// SummonerByPUUID returns the summoner of the account puuid on platform
+func (c *HTTPClient) SummonerByPUUID(ctx context.Context, platform Platform, puuid string) (*Summoner, error) {
+	summoner := &Summoner{}
+	err := c.get(ctx, string(platform), "summoner-v4.by-puuid", "/lol/summoner/v4/summoners/by-puuid/"+url.PathEscape(puuid), summoner)
+	if err != nil {
+		return nil, err
+	}
+	return summoner, nil
+}
+
+// ThirdPartyCode returns the verification code set by a summoner on platform
+func (c *HTTPClient) ThirdPartyCode(ctx context.Context, platform Platform, summonerID string) (string, error) {
+	var code string
+	err := c.get(ctx, string(platform), "third-party-code-v4.by-summoner", "/lol/platform/v4/third-party-code/by-summoner/"+url.PathEscape(summonerID), &code)
+	if err != nil {
+		return "", err
+	}
+	return code, nil
+}
+
+// LeagueEntries returns the ranked standings of a summoner on platform
+func (c *HTTPClient) LeagueEntries(ctx context.Context, platform Platform, summonerID string) ([]*LeagueEntry, error) {
+	var entries []*LeagueEntry
+	err := c.get(ctx, string(platform), "league-v4.entries-by-summoner", "/lol/league/v4/entries/by-summoner/"+url.PathEscape(summonerID), &entries)
+	if err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// get requests path on host, a platform or region, and decodes the json response into v
+// method names the endpoint for its rate limits
+func (c *HTTPClient) get(ctx context.Context, host, method, path string, v interface{}) error {
+	u := strings.Replace(c.baseURL, "{platform}", host, -1) + path
+	req, err := http.NewRequest("GET", u, nil)
+	if err != nil {
+		return err
+	}
+	req = req.WithContext(withScope(ctx, host, method))
+	req.Header.Set("X-Riot-Token", c.apiKey)
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrUnavailable, err)
+	}
+	defer resp.Body.Close()
+	if err := statusError(resp); err != nil {
+		io.Copy(ioutil.Discard, resp.Body)
+		return err
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+// statusError converts a response status to the error a Client returns
+func statusError(resp *http.Response) error {
+	switch code := resp.StatusCode; {
+	case code == http.StatusOK:
+		return nil
+	case code == http.StatusNotFound:
+		return fmt.Errorf("%w: %s", ErrNotFound, resp.Request.URL.Path)
+	case code == http.StatusTooManyRequests:
+		return fmt.Errorf("%w: retry after %ss", ErrRateLimited, resp.Header.Get("Retry-After"))
+	case code == http.StatusUnauthorized || code == http.StatusForbidden:
+		return fmt.Errorf("%w: api key rejected with status %d", ErrUnavailable, code)
+	case code >= 500:
+		return fmt.Errorf("%w: status %d", ErrUnavailable, code)
+	default:
+		return fmt.Errorf("riot: unexpected status %d", code)
+	}
+}
@@ -0,0 +1,293 @@
+package riot
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultAppLimits are the limits of a development api key, used until Riot reports the real ones
+const DefaultAppLimits = "20:1,100:120"
+
+type scopeKey struct{}
+
+type requestScope struct {
+	host   string
+	method string
+}
+
+// withScope records the host and method of a request so the transport can pick its limits
+// host is the platform or region the request is sent to
+func withScope(ctx context.Context, host, method string) context.Context {
+	return context.WithValue(ctx, scopeKey{}, requestScope{host: host, method: method})
+}
+
+// scopeOf returns the host and method of a request, falling back to its url host and path
+func scopeOf(req *http.Request) requestScope {
+	if s, ok := req.Context().Value(scopeKey{}).(requestScope); ok {
+		return s
+	}
+	return requestScope{host: req.URL.Host, method: req.URL.Path}
+}
+
+// bucket allows limit requests per window, refilling completely when the window ends
+type bucket struct {
+	limit     int
+	window    time.Duration
+	remaining int
+	resetAt   time.Time
+}
+
+func (b *bucket) refill(now time.Time) {
+	if !now.Before(b.resetAt) {
+		b.remaining = b.limit
+		b.resetAt = now.Add(b.window)
+	}
+}
+
+// limiter holds the buckets of an app or method limit and any Retry-After block
+type limiter struct {
+	header       string
+	buckets      []*bucket
+	blockedUntil time.Time
+}
+
+// delay returns how long to wait before a request fits every bucket
+func (l *limiter) delay(now time.Time) time.Duration {
+	var d time.Duration
+	if now.Before(l.blockedUntil) {
+		d = l.blockedUntil.Sub(now)
+	}
+	for _, b := range l.buckets {
+		b.refill(now)
+		if b.remaining <= 0 && b.resetAt.Sub(now) > d {
+			d = b.resetAt.Sub(now)
+		}
+	}
+	return d
+}
+
+func (l *limiter) take() {
+	for _, b := range l.buckets {
+		b.remaining--
+	}
+}
+
+// update replaces the buckets when Riot reports different limits and
+// lowers the remaining tokens to match the counts Riot has seen
+func (l *limiter) update(limits, counts string, now time.Time) {
+	if limits != "" && limits != l.header {
+		l.header = limits
+		l.buckets = nil
+		for _, lim := range parseLimits(limits) {
+			l.buckets = append(l.buckets, &bucket{limit: lim[0], window: time.Duration(lim[1]) * time.Second})
+		}
+	}
+	for _, count := range parseLimits(counts) {
+		for _, b := range l.buckets {
+			if b.window != time.Duration(count[1])*time.Second {
+				continue
+			}
+			b.refill(now)
+			if left := b.limit - count[0]; left < b.remaining {
+				b.remaining = left
+			}
+		}
+	}
+}
+
+// parseLimits parses a rate limit header such as "20:1,100:120" into count and seconds pairs
+func parseLimits(header string) [][2]int {
+	var limits [][2]int
+	for _, part := range strings.Split(header, ",") {
+		pair := strings.SplitN(strings.TrimSpace(part), ":", 2)
+		if len(pair) != 2 {
+			continue
+		}
+		count, err1 := strconv.Atoi(pair[0])
+		seconds, err2 := strconv.Atoi(pair[1])
+		if err1 != nil || err2 != nil || seconds <= 0 {
+			continue
+		}
+		limits = append(limits, [2]int{count, seconds})
+	}
+	return limits
+}
+
+// RateLimitedTransport is an http.RoundTripper that queues requests to stay under the
+// app and method limits Riot reports in X-App-Rate-Limit and X-Method-Rate-Limit,
+// honors Retry-After, and retries GET requests that fail transiently with backoff
+// app limits apply per platform or region and method limits per endpoint of each
+type RateLimitedTransport struct {
+	Base http.RoundTripper
+	// MaxRetries is how many times a request is retried after the first attempt
+	MaxRetries int
+	// Backoff is the delay before the first retry, doubled for each retry after
+	Backoff time.Duration
+
+	mu       sync.Mutex
+	limiters map[string]*limiter
+}
+
+// NewRateLimitedTransport creates a transport sending requests through base
+func NewRateLimitedTransport(base http.RoundTripper) *RateLimitedTransport {
+	return &RateLimitedTransport{
+		Base:       base,
+		MaxRetries: 3,
+		Backoff:    500 * time.Millisecond,
+		limiters:   map[string]*limiter{},
+	}
+}
+
+// limitersOf returns the app and method limiters of a request scope, t.mu must be held
+func (t *RateLimitedTransport) limitersOf(s requestScope) (app, method *limiter) {
+	appKey := "app " + s.host
+	methodKey := "method " + s.host + " " + s.method
+	app, ok := t.limiters[appKey]
+	if !ok {
+		app = &limiter{}
+		app.update(DefaultAppLimits, "", time.Now())
+		t.limiters[appKey] = app
+	}
+	method, ok = t.limiters[methodKey]
+	if !ok {
+		method = &limiter{}
+		t.limiters[methodKey] = method
+	}
+	return app, method
+}
+
+// wait blocks until the request fits its app and method limits and takes a token from each
+func (t *RateLimitedTransport) wait(ctx context.Context, s requestScope) error {
+	for {
+		t.mu.Lock()
+		now := time.Now()
+		app, method := t.limitersOf(s)
+		d := app.delay(now)
+		if md := method.delay(now); md > d {
+			d = md
+		}
+		if d == 0 {
+			app.take()
+			method.take()
+			t.mu.Unlock()
+			return nil
+		}
+		t.mu.Unlock()
+		if err := sleep(ctx, d); err != nil {
+			return err
+		}
+	}
+}
+
+// record updates the limits of a request scope from the headers of its response
+// and blocks the scope Riot named when it answered 429 with Retry-After
+func (t *RateLimitedTransport) record(s requestScope, resp *http.Response, retryAfter time.Duration) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	now := time.Now()
+	app, method := t.limitersOf(s)
+	app.update(resp.Header.Get("X-App-Rate-Limit"), resp.Header.Get("X-App-Rate-Limit-Count"), now)
+	method.update(resp.Header.Get("X-Method-Rate-Limit"), resp.Header.Get("X-Method-Rate-Limit-Count"), now)
+	if retryAfter <= 0 {
+		return
+	}
+	switch resp.Header.Get("X-Rate-Limit-Type") {
+	case "application":
+		app.blockedUntil = now.Add(retryAfter)
+	case "method":
+		method.blockedUntil = now.Add(retryAfter)
+	}
+}
+
+// RoundTrip sends req once its limits allow, retrying transient failures
+func (t *RateLimitedTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	base := t.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	s := scopeOf(req)
+	ctx := req.Context()
+	retryable := req.Method == http.MethodGet || req.Method == http.MethodHead
+	for attempt := 0; ; attempt++ {
+		if err := t.wait(ctx, s); err != nil {
+			return nil, err
+		}
+		resp, err := base.RoundTrip(req)
+		last := !retryable || attempt >= t.MaxRetries
+		if err != nil {
+			if last || ctx.Err() != nil {
+				return nil, err
+			}
+			if err := sleep(ctx, t.backoff(attempt)); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		retryAfter := parseRetryAfter(resp.Header.Get("Retry-After"))
+		t.record(s, resp, retryAfter)
+		if last || !transientStatus(resp.StatusCode) {
+			return resp, nil
+		}
+		io.Copy(ioutil.Discard, resp.Body)
+		resp.Body.Close()
+		d := t.backoff(attempt)
+		if retryAfter > 0 {
+			d = retryAfter
+		}
+		if err := sleep(ctx, d); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// backoff returns the delay before retry attempt+1, with up to 50% jitter
+func (t *RateLimitedTransport) backoff(attempt int) time.Duration {
+	d := t.Backoff << uint(attempt)
+	if d <= 0 {
+		return 0
+	}
+	return d + time.Duration(rand.Int63n(int64(d)/2+1))
+}
+
+// transientStatus reports whether a request that got status may succeed if retried
+func transientStatus(status int) bool {
+	switch status {
+	case http.StatusTooManyRequests, http.StatusInternalServerError, http.StatusBadGateway,
+		http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// parseRetryAfter reads a Retry-After header given in seconds or as a date
+func parseRetryAfter(header string) time.Duration {
+	if header == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(header); err == nil {
+		return time.Duration(seconds) * time.Second
+	}
+	if at, err := http.ParseTime(header); err == nil {
+		return time.Until(at)
+	}
+	return 0
+}
+
+// sleep waits for d or until ctx is done
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
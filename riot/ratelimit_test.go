@@ -0,0 +1,98 @@
+package riot
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseLimits(t *testing.T) {
+	tests := []struct {
+		header string
+		want   [][2]int
+	}{
+		{"20:1,100:120", [][2]int{{20, 1}, {100, 120}}},
+		{" 2000:60 ", [][2]int{{2000, 60}}},
+		{"20:1,bad,5:x,7:0,3:10", [][2]int{{20, 1}, {3, 10}}},
+		{"", nil},
+	}
+	for _, tt := range tests {
+		if got := parseLimits(tt.header); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("parseLimits(%q) = %v, want %v", tt.header, got, tt.want)
+		}
+	}
+}
+
+func TestLimiter(t *testing.T) {
+	now := time.Unix(1000, 0)
+	tests := []struct {
+		name   string
+		limits string
+		counts string
+		taken  int
+		at     time.Duration
+		want   time.Duration
+	}{
+		{"under the limit", "3:1,10:60", "", 2, 0, 0},
+		{"short window used up", "3:1,10:60", "", 3, 0, time.Second},
+		{"short window refilled", "3:1,10:60", "", 3, time.Second, 0},
+		{"long window used up", "10:1,5:60", "", 5, 2 * time.Second, 58 * time.Second},
+		{"counts seen by riot", "3:1,10:60", "10:60", 0, 0, time.Minute},
+		{"counts of another window", "3:1", "9:60", 0, 0, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := &limiter{}
+			l.update(tt.limits, tt.counts, now)
+			if d := l.delay(now); d != 0 && tt.taken > 0 {
+				t.Fatalf("first request waits %v", d)
+			}
+			for i := 0; i < tt.taken; i++ {
+				l.take()
+			}
+			if got := l.delay(now.Add(tt.at)); got != tt.want {
+				t.Errorf("delay = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLimiterUpdate(t *testing.T) {
+	now := time.Unix(1000, 0)
+	l := &limiter{}
+	l.update(DefaultAppLimits, "", now)
+	l.delay(now)
+	l.take()
+	l.update(DefaultAppLimits, "15:1", now)
+	if got := l.buckets[0].remaining; got != 5 {
+		t.Errorf("remaining after counts = %d, want 5", got)
+	}
+	l.update(DefaultAppLimits, "1:1", now)
+	if got := l.buckets[0].remaining; got != 5 {
+		t.Errorf("lower count raised remaining to %d", got)
+	}
+	l.update("50:10", "", now)
+	if len(l.buckets) != 1 || l.buckets[0].limit != 50 || l.header != "50:10" {
+		t.Errorf("buckets after new limits = %+v", l.buckets[0])
+	}
+	l.blockedUntil = now.Add(3 * time.Second)
+	if got := l.delay(now); got != 3*time.Second {
+		t.Errorf("delay while blocked = %v, want 3s", got)
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	if got := parseRetryAfter("2"); got != 2*time.Second {
+		t.Errorf("seconds = %v", got)
+	}
+	at := time.Now().Add(time.Minute).UTC().Format(http.TimeFormat)
+	if got := parseRetryAfter(at); got <= 50*time.Second || got > time.Minute {
+		t.Errorf("date = %v, want about a minute", got)
+	}
+	for _, header := range []string{"", "soon"} {
+		if got := parseRetryAfter(header); got != 0 {
+			t.Errorf("parseRetryAfter(%q) = %v, want 0", header, got)
+		}
+	}
+}
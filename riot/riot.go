@@ -0,0 +1,163 @@
+// Package riot is a client for the parts of the Riot Games API used to link
+// league accounts to summoners
+package riot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Platform is the Riot platform a summoner plays on, it picks the API host
+type Platform string
+
+// platforms of the Riot API
+const (
+	BR1  Platform = "br1"
+	EUN1 Platform = "eun1"
+	EUW1 Platform = "euw1"
+	JP1  Platform = "jp1"
+	KR   Platform = "kr"
+	LA1  Platform = "la1"
+	LA2  Platform = "la2"
+	NA1  Platform = "na1"
+	OC1  Platform = "oc1"
+	RU   Platform = "ru"
+	TR1  Platform = "tr1"
+)
+
+var platforms = []Platform{BR1, EUN1, EUW1, JP1, KR, LA1, LA2, NA1, OC1, RU, TR1}
+
+// ParsePlatform returns the platform named by s, ignoring case
+func ParsePlatform(s string) (Platform, error) {
+	for _, p := range platforms {
+		if strings.EqualFold(s, string(p)) {
+			return p, nil
+		}
+	}
+	return "", fmt.Errorf("riot: unknown platform %q", s)
+}
+
+// Region is a regional routing value, account-v1 and match-v5 are served per region
+// rather than per platform
+type Region string
+
+// regional routing values of the Riot API
+const (
+	Americas Region = "americas"
+	Asia     Region = "asia"
+	Europe   Region = "europe"
+	SEA      Region = "sea"
+)
+
+// Region returns the regional routing value that serves platform
+func (p Platform) Region() Region {
+	switch p {
+	case BR1, LA1, LA2, NA1:
+		return Americas
+	case JP1, KR:
+		return Asia
+	case EUN1, EUW1, RU, TR1:
+		return Europe
+	default:
+		return SEA
+	}
+}
+
+// accountRegion returns the region to look up accounts of platform in,
+// account-v1 is not served by sea but any region can find any account
+func (p Platform) accountRegion() Region {
+	if r := p.Region(); r != SEA {
+		return r
+	}
+	return Asia
+}
+
+// RiotID is the name a player is known by across Riot games, written gameName#tagLine
+type RiotID struct {
+	GameName string
+	TagLine  string
+}
+
+// ParseRiotID parses a Riot ID written gameName#tagLine
+func ParseRiotID(s string) (RiotID, error) {
+	i := strings.LastIndex(s, "#")
+	if i < 0 {
+		return RiotID{}, fmt.Errorf("riot: %q is not gameName#tagLine", s)
+	}
+	id := RiotID{GameName: strings.TrimSpace(s[:i]), TagLine: strings.TrimSpace(s[i+1:])}
+	if id.GameName == "" || id.TagLine == "" {
+		return RiotID{}, fmt.Errorf("riot: %q is not gameName#tagLine", s)
+	}
+	return id, nil
+}
+
+func (id RiotID) String() string {
+	return id.GameName + "#" + id.TagLine
+}
+
+// key folds a Riot ID the way Riot matches them, ignoring case
+func (id RiotID) key() string {
+	return strings.ToLower(id.String())
+}
+
+// errors returned by a Client, wrapped with details of the failed request
+var (
+	ErrNotFound    = errors.New("riot: not found")
+	ErrRateLimited = errors.New("riot: rate limited")
+	ErrUnavailable = errors.New("riot: api unavailable")
+)
+
+// Account is a Riot account as returned by account-v1, its PUUID never changes
+type Account struct {
+	PUUID    string `json:"puuid"`
+	GameName string `json:"gameName"`
+	TagLine  string `json:"tagLine"`
+}
+
+// Summoner is the league profile of an account on one platform as returned by summoner-v4
+// ID and AccountID are encrypted for the api key that requested them
+type Summoner struct {
+	ID            string `json:"id"`
+	AccountID     string `json:"accountId"`
+	PUUID         string `json:"puuid"`
+	ProfileIconID int    `json:"profileIconId"`
+	SummonerLevel int64  `json:"summonerLevel"`
+	RevisionDate  int64  `json:"revisionDate"`
+}
+
+// ranked queues reported by league-v4
+const (
+	QueueSolo = "RANKED_SOLO_5x5"
+	QueueFlex = "RANKED_FLEX_SR"
+)
+
+// LeagueEntry is the standing of a summoner in one ranked queue as returned by league-v4
+// Rank is the division within Tier, empty for the apex tiers
+type LeagueEntry struct {
+	LeagueID     string `json:"leagueId"`
+	SummonerID   string `json:"summonerId"`
+	QueueType    string `json:"queueType"`
+	Tier         string `json:"tier"`
+	Rank         string `json:"rank"`
+	LeaguePoints int    `json:"leaguePoints"`
+	Wins         int    `json:"wins"`
+	Losses       int    `json:"losses"`
+}
+
+// Client looks up accounts, summoners and their ranks
+type Client interface {
+	// AccountByRiotID returns the account known as id or an error wrapping ErrNotFound
+	// platform picks the region the account is looked up in
+	AccountByRiotID(ctx context.Context, platform Platform, id RiotID) (*Account, error)
+	// SummonerByPUUID returns the summoner of an account on platform
+	// or an error wrapping ErrNotFound if it has never played there
+	SummonerByPUUID(ctx context.Context, platform Platform, puuid string) (*Summoner, error)
+	// ThirdPartyCode returns the verification code a summoner has set in the client
+	// or an error wrapping ErrNotFound if none is set
+	ThirdPartyCode(ctx context.Context, platform Platform, summonerID string) (string, error)
+	// LeagueEntries returns the ranked standings of a summoner on platform,
+	// empty if it is unranked in every queue
+	LeagueEntries(ctx context.Context, platform Platform, summonerID string) ([]*LeagueEntry, error)
+}
@@ -0,0 +1,128 @@
+package riot
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// Stub is an in-memory Client for tests and local development
+// accounts are looked up by region and Riot ID the way Riot matches them
+type Stub struct {
+	// Err is returned by every call when set, to simulate a failing api
+	Err error
+
+	mu        sync.Mutex
+	accounts  map[Region]map[string]*Account
+	summoners map[Platform]map[string]*Summoner
+	codes     map[Platform]map[string]string
+	leagues   map[Platform]map[string][]*LeagueEntry
+}
+
+var _ Client = (*Stub)(nil)
+
+// NewStub creates a Stub with no accounts
+func NewStub() *Stub {
+	return &Stub{
+		accounts:  map[Region]map[string]*Account{},
+		summoners: map[Platform]map[string]*Summoner{},
+		codes:     map[Platform]map[string]string{},
+		leagues:   map[Platform]map[string][]*LeagueEntry{},
+	}
+}
+
+// AddSummoner adds account and its summoner on platform with its third party code,
+// an empty code sets none
+func (s *Stub) AddSummoner(platform Platform, account *Account, summoner *Summoner, code string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	region := platform.accountRegion()
+	if s.accounts[region] == nil {
+		s.accounts[region] = map[string]*Account{}
+	}
+	if s.summoners[platform] == nil {
+		s.summoners[platform] = map[string]*Summoner{}
+		s.codes[platform] = map[string]string{}
+	}
+	copiedAccount := *account
+	s.accounts[region][RiotID{GameName: account.GameName, TagLine: account.TagLine}.key()] = &copiedAccount
+	copiedSummoner := *summoner
+	copiedSummoner.PUUID = account.PUUID
+	s.summoners[platform][account.PUUID] = &copiedSummoner
+	if code != "" {
+		s.codes[platform][summoner.ID] = code
+	}
+}
+
+// SetLeagueEntries replaces the ranked standings of a summoner on platform
+func (s *Stub) SetLeagueEntries(platform Platform, summonerID string, entries []*LeagueEntry) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.leagues[platform] == nil {
+		s.leagues[platform] = map[string][]*LeagueEntry{}
+	}
+	s.leagues[platform][summonerID] = copyEntries(entries)
+}
+
+func copyEntries(entries []*LeagueEntry) []*LeagueEntry {
+	copied := make([]*LeagueEntry, 0, len(entries))
+	for _, entry := range entries {
+		e := *entry
+		copied = append(copied, &e)
+	}
+	return copied
+}
+
+// AccountByRiotID returns the account known as id in the region of platform
+func (s *Stub) AccountByRiotID(ctx context.Context, platform Platform, id RiotID) (*Account, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.Err != nil {
+		return nil, s.Err
+	}
+	account, ok := s.accounts[platform.accountRegion()][id.key()]
+	if !ok {
+		return nil, fmt.Errorf("%w: account %s", ErrNotFound, id)
+	}
+	copied := *account
+	return &copied, nil
+}
+
+// SummonerByPUUID returns the summoner of the account puuid on platform
+func (s *Stub) SummonerByPUUID(ctx context.Context, platform Platform, puuid string) (*Summoner, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.Err != nil {
+		return nil, s.Err
+	}
+	summoner, ok := s.summoners[platform][puuid]
+	if !ok {
+		return nil, fmt.Errorf("%w: summoner %s on %s", ErrNotFound, puuid, platform)
+	}
+	copied := *summoner
+	return &copied, nil
+}
+
+// ThirdPartyCode returns the code set by a summoner on platform
+func (s *Stub) ThirdPartyCode(ctx context.Context, platform Platform, summonerID string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.Err != nil {
+		return "", s.Err
+	}
+	code, ok := s.codes[platform][summonerID]
+	if !ok {
+		return "", fmt.Errorf("%w: third party code of %s on %s", ErrNotFound, summonerID, platform)
+	}
+	return code, nil
+}
+
+// LeagueEntries returns the ranked standings of a summoner on platform
+func (s *Stub) LeagueEntries(ctx context.Context, platform Platform, summonerID string) ([]*LeagueEntry, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.Err != nil {
+		return nil, s.Err
+	}
+	return copyEntries(s.leagues[platform][summonerID]), nil
+}
@@ -0,0 +1,146 @@
+package riot_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/anthonyrouseau/lss-api/riot"
+	"github.com/anthonyrouseau/lss-api/riotfake"
+)
+
+// faultTransport asks riotfake for the next status of statuses on each attempt,
+// the last status repeats once they run out
+type faultTransport struct {
+	mu       sync.Mutex
+	statuses []int
+	attempts int
+}
+
+func (f *faultTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	f.mu.Lock()
+	status := f.statuses[len(f.statuses)-1]
+	if f.attempts < len(f.statuses) {
+		status = f.statuses[f.attempts]
+	}
+	f.attempts++
+	f.mu.Unlock()
+	req = req.Clone(req.Context())
+	req.Header.Set(riotfake.FaultHeader, strconv.Itoa(status))
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+func newRiotfake(t *testing.T) *httptest.Server {
+	fixture, err := riotfake.LoadFixture("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := httptest.NewServer(riotfake.NewServer(fixture))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestRateLimitedTransport(t *testing.T) {
+	srv := newRiotfake(t)
+	const backoff = 20 * time.Millisecond
+	tests := []struct {
+		name     string
+		method   string
+		statuses []int
+		status   int
+		attempts int
+		minWait  time.Duration
+	}{
+		{"success", "GET", []int{200}, 200, 1, 0},
+		{"not found is not retried", "GET", []int{404}, 404, 1, 0},
+		{"unavailable then success", "GET", []int{503, 200}, 200, 2, backoff},
+		{"backoff doubles", "GET", []int{500, 502, 200}, 200, 3, 3 * backoff},
+		{"retries run out", "GET", []int{503}, 503, 4, 7 * backoff},
+		{"rate limited waits for retry after", "GET", []int{429, 200}, 200, 2, time.Second},
+		{"post is not retried", "POST", []int{503, 200}, 503, 1, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			faults := &faultTransport{statuses: tt.statuses}
+			transport := riot.NewRateLimitedTransport(faults)
+			transport.Backoff = backoff
+			req, err := http.NewRequest(tt.method, srv.URL+"/na1/lol/summoner/v4/summoners/kTL0MSDkGgKHqb3TnGkPoZ2Ayj5sJHMNEHqAa1RcpqVv0sA", nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			start := time.Now()
+			resp, err := transport.RoundTrip(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp.Body.Close()
+			if resp.StatusCode != tt.status {
+				t.Errorf("status = %d, want %d", resp.StatusCode, tt.status)
+			}
+			if faults.attempts != tt.attempts {
+				t.Errorf("attempts = %d, want %d", faults.attempts, tt.attempts)
+			}
+			if waited := time.Since(start); waited < tt.minWait {
+				t.Errorf("waited %v, want at least %v", waited, tt.minWait)
+			}
+		})
+	}
+}
+
+func TestRateLimitedTransportBlocksMethod(t *testing.T) {
+	srv := newRiotfake(t)
+	faults := &faultTransport{statuses: []int{429, 200}}
+	transport := riot.NewRateLimitedTransport(faults)
+	transport.MaxRetries = 0
+	path := srv.URL + "/na1/lol/summoner/v4/summoners/kTL0MSDkGgKHqb3TnGkPoZ2Ayj5sJHMNEHqAa1RcpqVv0sA"
+
+	req, _ := http.NewRequest("GET", path, nil)
+	resp, err := transport.RoundTrip(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusTooManyRequests {
+		t.Fatalf("status = %d, want %d", resp.StatusCode, http.StatusTooManyRequests)
+	}
+
+	// riotfake blocks the method for its Retry-After of one second
+	start := time.Now()
+	req, _ = http.NewRequest("GET", path, nil)
+	resp, err = transport.RoundTrip(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if waited := time.Since(start); waited < 900*time.Millisecond {
+		t.Errorf("next request waited %v, want the Retry-After", waited)
+	}
+
+	// other methods are not blocked
+	start = time.Now()
+	req, _ = http.NewRequest("GET", srv.URL+"/na1/lol/league/v4/entries/by-summoner/kTL0MSDkGgKHqb3TnGkPoZ2Ayj5sJHMNEHqAa1RcpqVv0sA", nil)
+	resp, err = transport.RoundTrip(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if waited := time.Since(start); waited > 500*time.Millisecond {
+		t.Errorf("another method waited %v", waited)
+	}
+}
+
+func TestRateLimitedTransportCanceled(t *testing.T) {
+	srv := newRiotfake(t)
+	transport := riot.NewRateLimitedTransport(&faultTransport{statuses: []int{503}})
+	transport.Backoff = time.Minute
+	req, _ := http.NewRequest("GET", srv.URL+"/na1/lol/summoner/v4/summoners/kTL0MSDkGgKHqb3TnGkPoZ2Ayj5sJHMNEHqAa1RcpqVv0sA", nil)
+	ctx, cancel := context.WithTimeout(req.Context(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := transport.RoundTrip(req.WithContext(ctx)); err != context.DeadlineExceeded {
+		t.Errorf("round trip = %v, want %v", err, context.DeadlineExceeded)
+	}
+}
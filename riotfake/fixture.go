@@ -0,0 +1,89 @@
+package riotfake
+
+import (
+	_ "embed"
+	"encoding/json"
+	"io/ioutil"
+
+	"github.com/anthonyrouseau/lss-api/riot"
+)
+
+// defaultFixture seeds a few summoners on na1 and euw1 so the server is useful without a fixture file
+//
+//go:embed fixture.json
+var defaultFixture []byte
+
+// Fixture is the data served by the fake, keyed by platform
+type Fixture map[riot.Platform]*PlatformFixture
+
+// PlatformFixture holds the summoners and matches of one platform
+type PlatformFixture struct {
+	Summoners []*SummonerFixture `json:"summoners"`
+	Matches   []*Match           `json:"matches"`
+}
+
+// SummonerFixture is a summoner with the Riot ID of its account,
+// the third party code it has set and its league entries
+type SummonerFixture struct {
+	riot.Summoner
+	GameName string              `json:"gameName"`
+	TagLine  string              `json:"tagLine"`
+	Code     string              `json:"code,omitempty"`
+	Leagues  []*riot.LeagueEntry `json:"leagues,omitempty"`
+}
+
+// account returns the account-v1 view of a summoner
+func (s *SummonerFixture) account() *riot.Account {
+	return &riot.Account{PUUID: s.PUUID, GameName: s.GameName, TagLine: s.TagLine}
+}
+
+// Match is a finished game as returned by match-v5
+type Match struct {
+	Metadata MatchMetadata `json:"metadata"`
+	Info     MatchInfo     `json:"info"`
+}
+
+// MatchMetadata identifies a match and the PUUIDs of its players
+type MatchMetadata struct {
+	MatchID      string   `json:"matchId"`
+	Participants []string `json:"participants"`
+}
+
+// MatchInfo describes how a match was played
+type MatchInfo struct {
+	PlatformID   string         `json:"platformId"`
+	QueueID      int            `json:"queueId"`
+	GameCreation int64          `json:"gameCreation"`
+	GameDuration int64          `json:"gameDuration"`
+	Participants []*Participant `json:"participants"`
+}
+
+// Participant is one player of a match
+type Participant struct {
+	PUUID        string `json:"puuid"`
+	SummonerID   string `json:"summonerId"`
+	ChampionID   int    `json:"championId"`
+	TeamPosition string `json:"teamPosition"`
+	Win          bool   `json:"win"`
+}
+
+// ParseFixture decodes a fixture from json
+func ParseFixture(data []byte) (Fixture, error) {
+	fixture := Fixture{}
+	if err := json.Unmarshal(data, &fixture); err != nil {
+		return nil, err
+	}
+	return fixture, nil
+}
+
+// LoadFixture reads a fixture from a json file, an empty path loads the built in fixture
+func LoadFixture(path string) (Fixture, error) {
+	if path == "" {
+		return ParseFixture(defaultFixture)
+	}
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return ParseFixture(data)
+}
@@ -0,0 +1,412 @@
+// Package riotfake imitates the parts of the Riot Games API used by lss-api,
+// serving accounts, summoners, third party codes, league entries and matches from a fixture
+//
+// requests are routed by a platform or region prefix, so a riot client should use
+// a base url such as http://localhost:8089/{platform}
+package riotfake
+
+import (
+	"encoding/json"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/anthonyrouseau/lss-api/riot"
+	"github.com/go-chi/chi"
+)
+
+// FaultHeader forces a response status for one request, for tests that need a specific failure
+const FaultHeader = "X-Riotfake-Status"
+
+// limits reported in the rate limit headers of every response
+const (
+	appRateLimit    = "20:1,100:120"
+	methodRateLimit = "2000:60"
+)
+
+// Faults are failures the server injects into requests
+// rates are probabilities between 0 and 1
+type Faults struct {
+	// Latency is added before every response, in nanoseconds in json
+	Latency time.Duration `json:"latency"`
+	// NotFoundRate is how often a request gets 404 whether or not the data exists
+	NotFoundRate float64 `json:"notFoundRate"`
+	// RateLimitRate is how often a request gets 429
+	RateLimitRate float64 `json:"rateLimitRate"`
+	// RetryAfter is the Retry-After of a 429 in seconds
+	RetryAfter int `json:"retryAfter"`
+}
+
+// Server is an http.Handler imitating the Riot API
+type Server struct {
+	// APIKey is required in X-Riot-Token when set
+	APIKey string
+
+	mu      sync.RWMutex
+	fixture Fixture
+	faults  Faults
+	router  chi.Router
+}
+
+// NewServer creates a Server serving fixture with no faults
+func NewServer(fixture Fixture) *Server {
+	s := &Server{fixture: fixture, faults: Faults{RetryAfter: 1}}
+	r := chi.NewRouter()
+	r.Get("/riotfake/faults", s.getFaults)
+	r.Put("/riotfake/faults", s.putFaults)
+	r.Put("/riotfake/{host}/third-party-code/{summonerID}", s.putThirdPartyCode)
+	r.Put("/riotfake/{host}/leagues/{summonerID}", s.putLeagueEntries)
+	r.Route("/{host}", func(r chi.Router) {
+		r.Use(s.checkKey, s.injectFaults)
+		r.Get("/riot/account/v1/accounts/by-riot-id/{gameName}/{tagLine}", s.accountByRiotID)
+		r.Get("/riot/account/v1/accounts/by-puuid/{puuid}", s.accountByPUUID)
+		r.Get("/lol/summoner/v4/summoners/by-puuid/{puuid}", s.summonerByPUUID)
+		r.Get("/lol/summoner/v4/summoners/{summonerID}", s.summonerByID)
+		r.Get("/lol/platform/v4/third-party-code/by-summoner/{summonerID}", s.thirdPartyCode)
+		r.Get("/lol/league/v4/entries/by-summoner/{summonerID}", s.leagueEntries)
+		r.Get("/lol/match/v5/matches/by-puuid/{puuid}/ids", s.matchIDs)
+		r.Get("/lol/match/v5/matches/{matchID}", s.match)
+	})
+	r.NotFound(func(w http.ResponseWriter, r *http.Request) {
+		writeStatus(w, http.StatusNotFound, "Not found")
+	})
+	s.router = r
+	return s
+}
+
+// SetFaults replaces the faults injected into requests
+func (s *Server) SetFaults(f Faults) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.faults = f
+}
+
+// SetThirdPartyCode sets the code a summoner on platform has entered in the client,
+// as a player would while linking their account, and reports whether the summoner exists
+func (s *Server) SetThirdPartyCode(platform riot.Platform, summonerID, code string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	p := s.fixture[platform]
+	if p == nil {
+		return false
+	}
+	for _, summoner := range p.Summoners {
+		if summoner.ID == summonerID {
+			summoner.Code = code
+			return true
+		}
+	}
+	return false
+}
+
+// SetLeagueEntries replaces the ranked standings of a summoner on platform,
+// as if it had played ranked games, and reports whether the summoner exists
+func (s *Server) SetLeagueEntries(platform riot.Platform, summonerID string, entries []*riot.LeagueEntry) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	p := s.fixture[platform]
+	if p == nil {
+		return false
+	}
+	for _, summoner := range p.Summoners {
+		if summoner.ID == summonerID {
+			for _, entry := range entries {
+				entry.SummonerID = summonerID
+			}
+			summoner.Leagues = entries
+			return true
+		}
+	}
+	return false
+}
+
+// ServeHTTP routes a request to the imitated endpoint
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.router.ServeHTTP(w, r)
+}
+
+// writeStatus writes an error in the format Riot uses
+func writeStatus(w http.ResponseWriter, status int, message string) {
+	w.Header().Set("Content-Type", "application/json;charset=utf-8")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"status": map[string]interface{}{"message": message, "status_code": status},
+	})
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json;charset=utf-8")
+	json.NewEncoder(w).Encode(v)
+}
+
+func (s *Server) getFaults(w http.ResponseWriter, r *http.Request) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	writeJSON(w, s.faults)
+}
+
+func (s *Server) putFaults(w http.ResponseWriter, r *http.Request) {
+	var f Faults
+	if err := json.NewDecoder(r.Body).Decode(&f); err != nil {
+		writeStatus(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	s.SetFaults(f)
+	writeJSON(w, f)
+}
+
+func (s *Server) putThirdPartyCode(w http.ResponseWriter, r *http.Request) {
+	var code string
+	if err := json.NewDecoder(r.Body).Decode(&code); err != nil {
+		writeStatus(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	platform, err := riot.ParsePlatform(chi.URLParam(r, "host"))
+	if err != nil || !s.SetThirdPartyCode(platform, pathParam(r, "summonerID"), code) {
+		writeStatus(w, http.StatusNotFound, "Data not found - summoner not found")
+		return
+	}
+	writeJSON(w, code)
+}
+
+func (s *Server) putLeagueEntries(w http.ResponseWriter, r *http.Request) {
+	var entries []*riot.LeagueEntry
+	if err := json.NewDecoder(r.Body).Decode(&entries); err != nil {
+		writeStatus(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	platform, err := riot.ParsePlatform(chi.URLParam(r, "host"))
+	if err != nil || !s.SetLeagueEntries(platform, pathParam(r, "summonerID"), entries) {
+		writeStatus(w, http.StatusNotFound, "Data not found - summoner not found")
+		return
+	}
+	writeJSON(w, entries)
+}
+
+// checkKey rejects requests without the api key when one is set
+func (s *Server) checkKey(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if s.APIKey != "" && r.Header.Get("X-Riot-Token") != s.APIKey {
+			writeStatus(w, http.StatusForbidden, "Forbidden")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// injectFaults delays requests and fails them as configured or as asked by FaultHeader
+func (s *Server) injectFaults(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mu.RLock()
+		f := s.faults
+		s.mu.RUnlock()
+		if f.Latency > 0 {
+			select {
+			case <-time.After(f.Latency):
+			case <-r.Context().Done():
+				return
+			}
+		}
+		w.Header().Set("X-App-Rate-Limit", appRateLimit)
+		w.Header().Set("X-Method-Rate-Limit", methodRateLimit)
+		status := 0
+		if forced, err := strconv.Atoi(r.Header.Get(FaultHeader)); err == nil && forced != http.StatusOK {
+			status = forced
+		} else if rand.Float64() < f.RateLimitRate {
+			status = http.StatusTooManyRequests
+		} else if rand.Float64() < f.NotFoundRate {
+			status = http.StatusNotFound
+		}
+		switch status {
+		case 0:
+			next.ServeHTTP(w, r)
+		case http.StatusTooManyRequests:
+			w.Header().Set("Retry-After", strconv.Itoa(f.RetryAfter))
+			w.Header().Set("X-Rate-Limit-Type", "method")
+			writeStatus(w, status, "Rate limit exceeded")
+		default:
+			writeStatus(w, status, http.StatusText(status))
+		}
+	})
+}
+
+// platform returns the fixture of the platform the request was sent to or nil
+func (s *Server) platform(r *http.Request) *PlatformFixture {
+	p, err := riot.ParsePlatform(chi.URLParam(r, "host"))
+	if err != nil {
+		return nil
+	}
+	return s.fixture[p]
+}
+
+// region returns the fixtures of the platforms served by the region the request was sent to
+func (s *Server) region(r *http.Request) []*PlatformFixture {
+	var fixtures []*PlatformFixture
+	region := riot.Region(strings.ToLower(chi.URLParam(r, "host")))
+	for p, fixture := range s.fixture {
+		if p.Region() == region {
+			fixtures = append(fixtures, fixture)
+		}
+	}
+	return fixtures
+}
+
+// isRegion reports whether the request was sent to a region rather than a platform
+func isRegion(r *http.Request) bool {
+	switch riot.Region(strings.ToLower(chi.URLParam(r, "host"))) {
+	case riot.Americas, riot.Asia, riot.Europe, riot.SEA:
+		return true
+	}
+	return false
+}
+
+// summoner returns the summoner matching pred on the platform of the request or nil
+func (s *Server) summoner(r *http.Request, pred func(*SummonerFixture) bool) *SummonerFixture {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	p := s.platform(r)
+	if p == nil {
+		return nil
+	}
+	for _, summoner := range p.Summoners {
+		if pred(summoner) {
+			return summoner
+		}
+	}
+	return nil
+}
+
+// account returns the account matching pred on any platform, as any region can find any account
+func (s *Server) account(pred func(*SummonerFixture) bool) *riot.Account {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for _, p := range s.fixture {
+		for _, summoner := range p.Summoners {
+			if pred(summoner) {
+				return summoner.account()
+			}
+		}
+	}
+	return nil
+}
+
+// pathParam returns an unescaped path parameter
+func pathParam(r *http.Request, key string) string {
+	value := chi.URLParam(r, key)
+	if unescaped, err := url.PathUnescape(value); err == nil {
+		return unescaped
+	}
+	return value
+}
+
+func (s *Server) accountByRiotID(w http.ResponseWriter, r *http.Request) {
+	if !isRegion(r) {
+		writeStatus(w, http.StatusNotFound, "Not found")
+		return
+	}
+	gameName, tagLine := pathParam(r, "gameName"), pathParam(r, "tagLine")
+	account := s.account(func(summoner *SummonerFixture) bool {
+		return strings.EqualFold(summoner.GameName, gameName) && strings.EqualFold(summoner.TagLine, tagLine)
+	})
+	if account == nil {
+		writeStatus(w, http.StatusNotFound, "Data not found - No results found for player with riot id "+gameName+"#"+tagLine)
+		return
+	}
+	writeJSON(w, account)
+}
+
+func (s *Server) accountByPUUID(w http.ResponseWriter, r *http.Request) {
+	if !isRegion(r) {
+		writeStatus(w, http.StatusNotFound, "Not found")
+		return
+	}
+	puuid := pathParam(r, "puuid")
+	account := s.account(func(summoner *SummonerFixture) bool { return summoner.PUUID == puuid })
+	if account == nil {
+		writeStatus(w, http.StatusNotFound, "Data not found - account not found")
+		return
+	}
+	writeJSON(w, account)
+}
+
+func (s *Server) summonerByPUUID(w http.ResponseWriter, r *http.Request) {
+	puuid := pathParam(r, "puuid")
+	summoner := s.summoner(r, func(summoner *SummonerFixture) bool { return summoner.PUUID == puuid })
+	if summoner == nil {
+		writeStatus(w, http.StatusNotFound, "Data not found - summoner not found")
+		return
+	}
+	writeJSON(w, summoner.Summoner)
+}
+
+func (s *Server) summonerByID(w http.ResponseWriter, r *http.Request) {
+	id := pathParam(r, "summonerID")
+	summoner := s.summoner(r, func(summoner *SummonerFixture) bool { return summoner.ID == id })
+	if summoner == nil {
+		writeStatus(w, http.StatusNotFound, "Data not found - summoner not found")
+		return
+	}
+	writeJSON(w, summoner.Summoner)
+}
+
+func (s *Server) thirdPartyCode(w http.ResponseWriter, r *http.Request) {
+	id := pathParam(r, "summonerID")
+	summoner := s.summoner(r, func(summoner *SummonerFixture) bool { return summoner.ID == id })
+	if summoner == nil || summoner.Code == "" {
+		writeStatus(w, http.StatusNotFound, "Data not found")
+		return
+	}
+	writeJSON(w, summoner.Code)
+}
+
+func (s *Server) leagueEntries(w http.ResponseWriter, r *http.Request) {
+	id := pathParam(r, "summonerID")
+	summoner := s.summoner(r, func(summoner *SummonerFixture) bool { return summoner.ID == id })
+	if summoner == nil {
+		writeStatus(w, http.StatusNotFound, "Data not found - summoner not found")
+		return
+	}
+	s.mu.RLock()
+	entries := summoner.Leagues
+	s.mu.RUnlock()
+	if entries == nil {
+		entries = []*riot.LeagueEntry{}
+	}
+	writeJSON(w, entries)
+}
+
+func (s *Server) matchIDs(w http.ResponseWriter, r *http.Request) {
+	puuid := pathParam(r, "puuid")
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	ids := []string{}
+	for _, p := range s.region(r) {
+		for _, match := range p.Matches {
+			for _, participant := range match.Info.Participants {
+				if participant.PUUID == puuid {
+					ids = append(ids, match.Metadata.MatchID)
+				}
+			}
+		}
+	}
+	writeJSON(w, ids)
+}
+
+func (s *Server) match(w http.ResponseWriter, r *http.Request) {
+	matchID := pathParam(r, "matchID")
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for _, p := range s.region(r) {
+		for _, match := range p.Matches {
+			if match.Metadata.MatchID == matchID {
+				writeJSON(w, match)
+				return
+			}
+		}
+	}
+	writeStatus(w, http.StatusNotFound, "Data not found - match file not found")
+}
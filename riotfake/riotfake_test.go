@@ -0,0 +1,229 @@
+package riotfake_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/anthonyrouseau/lss-api/riot"
+	"github.com/anthonyrouseau/lss-api/riotfake"
+)
+
+// summoners of the built in fixture
+const (
+	fakerFanID    = "kTL0MSDkGgKHqb3TnGkPoZ2Ayj5sJHMNEHqAa1RcpqVv0sA"
+	fakerFanPUUID = "xC7mGE3Q8ew7TuTtqJ5N0Ad1hFv0bO6Xmw3lRUqzB9WJp9o2Vb4oLQZk1Q7YgD0ZcTl9f2SgKpE1Ww"
+	noCodeSetID   = "Hq2Vx5mN8bT3kR0cL7yP1wJ4sD9fG6aZ2eU5iO8nB3vX1q"
+)
+
+func newServer(t *testing.T) (*riotfake.Server, *httptest.Server) {
+	fixture, err := riotfake.LoadFixture("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fake := riotfake.NewServer(fixture)
+	srv := httptest.NewServer(fake)
+	t.Cleanup(srv.Close)
+	return fake, srv
+}
+
+func TestClientAgainstFake(t *testing.T) {
+	fake, srv := newServer(t)
+	client := riot.NewHTTPClient(srv.URL+"/{platform}", "", 5*time.Second)
+	ctx := context.Background()
+
+	account, err := client.AccountByRiotID(ctx, riot.NA1, riot.RiotID{GameName: "faker fan", TagLine: "na1"})
+	if err != nil || account.PUUID != fakerFanPUUID || account.GameName != "Faker Fan" {
+		t.Fatalf("account = %+v, %v", account, err)
+	}
+	summoner, err := client.SummonerByPUUID(ctx, riot.NA1, fakerFanPUUID)
+	if err != nil || summoner.ID != fakerFanID {
+		t.Fatalf("summoner = %+v, %v", summoner, err)
+	}
+	if code, err := client.ThirdPartyCode(ctx, riot.NA1, fakerFanID); err != nil || code != "lss-dev" {
+		t.Errorf("code = %q, %v", code, err)
+	}
+	entries, err := client.LeagueEntries(ctx, riot.NA1, fakerFanID)
+	if err != nil || len(entries) != 2 || entries[0].QueueType != riot.QueueSolo || entries[0].Tier != "GOLD" {
+		t.Errorf("league entries = %+v, %v", entries, err)
+	}
+
+	notFound := []struct {
+		name string
+		call func() error
+	}{
+		{"unknown riot id", func() error {
+			_, err := client.AccountByRiotID(ctx, riot.NA1, riot.RiotID{GameName: "Nobody", TagLine: "NA1"})
+			return err
+		}},
+		{"summoner of another platform", func() error {
+			_, err := client.SummonerByPUUID(ctx, riot.EUW1, fakerFanPUUID)
+			return err
+		}},
+		{"no code set", func() error {
+			_, err := client.ThirdPartyCode(ctx, riot.NA1, noCodeSetID)
+			return err
+		}},
+	}
+	for _, tt := range notFound {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.call(); !errors.Is(err, riot.ErrNotFound) {
+				t.Errorf("err = %v, want %v", err, riot.ErrNotFound)
+			}
+		})
+	}
+
+	if !fake.SetThirdPartyCode(riot.NA1, noCodeSetID, "lss-123") {
+		t.Fatal("summoner without a code not found")
+	}
+	if code, err := client.ThirdPartyCode(ctx, riot.NA1, noCodeSetID); err != nil || code != "lss-123" {
+		t.Errorf("code after setting it = %q, %v", code, err)
+	}
+	diamond := []*riot.LeagueEntry{{QueueType: riot.QueueSolo, Tier: "DIAMOND", Rank: "IV"}}
+	if !fake.SetLeagueEntries(riot.NA1, noCodeSetID, diamond) {
+		t.Fatal("summoner without leagues not found")
+	}
+	entries, err = client.LeagueEntries(ctx, riot.NA1, noCodeSetID)
+	if err != nil || len(entries) != 1 || entries[0].Tier != "DIAMOND" || entries[0].SummonerID != noCodeSetID {
+		t.Errorf("league entries after setting them = %+v, %v", entries, err)
+	}
+	if fake.SetThirdPartyCode(riot.KR, noCodeSetID, "lss-123") {
+		t.Error("set a code on a platform without the summoner")
+	}
+}
+
+func TestAPIKey(t *testing.T) {
+	fake, srv := newServer(t)
+	fake.APIKey = "secret"
+	ctx := context.Background()
+	if _, err := riot.NewHTTPClient(srv.URL+"/{platform}", "wrong", 5*time.Second).SummonerByPUUID(ctx, riot.NA1, fakerFanPUUID); !errors.Is(err, riot.ErrUnavailable) {
+		t.Errorf("wrong key: %v, want %v", err, riot.ErrUnavailable)
+	}
+	if _, err := riot.NewHTTPClient(srv.URL+"/{platform}", "secret", 5*time.Second).SummonerByPUUID(ctx, riot.NA1, fakerFanPUUID); err != nil {
+		t.Errorf("right key: %v", err)
+	}
+}
+
+func TestFaults(t *testing.T) {
+	fake, srv := newServer(t)
+	summonerURL := srv.URL + "/na1/lol/summoner/v4/summoners/" + fakerFanID
+	tests := []struct {
+		name   string
+		faults riotfake.Faults
+		header string
+		status int
+	}{
+		{"none", riotfake.Faults{}, "", http.StatusOK},
+		{"forced status", riotfake.Faults{}, "503", http.StatusServiceUnavailable},
+		{"forced 200 leaves the rates", riotfake.Faults{NotFoundRate: 1}, "200", http.StatusNotFound},
+		{"not found rate", riotfake.Faults{NotFoundRate: 1}, "", http.StatusNotFound},
+		{"rate limit rate", riotfake.Faults{RateLimitRate: 1, RetryAfter: 7}, "", http.StatusTooManyRequests},
+		{"latency", riotfake.Faults{Latency: 50 * time.Millisecond}, "", http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake.SetFaults(tt.faults)
+			req, _ := http.NewRequest("GET", summonerURL, nil)
+			if tt.header != "" {
+				req.Header.Set(riotfake.FaultHeader, tt.header)
+			}
+			start := time.Now()
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp.Body.Close()
+			if resp.StatusCode != tt.status {
+				t.Errorf("status = %d, want %d", resp.StatusCode, tt.status)
+			}
+			if resp.Header.Get("X-App-Rate-Limit") == "" || resp.Header.Get("X-Method-Rate-Limit") == "" {
+				t.Errorf("rate limit headers missing: %v", resp.Header)
+			}
+			if tt.status == http.StatusTooManyRequests && resp.Header.Get("Retry-After") != strconv.Itoa(tt.faults.RetryAfter) {
+				t.Errorf("Retry-After = %q, want %d", resp.Header.Get("Retry-After"), tt.faults.RetryAfter)
+			}
+			if waited := time.Since(start); waited < tt.faults.Latency {
+				t.Errorf("answered after %v, want at least %v", waited, tt.faults.Latency)
+			}
+		})
+	}
+}
+
+func TestControlEndpoints(t *testing.T) {
+	_, srv := newServer(t)
+	put := func(path, body string) int {
+		req, _ := http.NewRequest("PUT", srv.URL+path, strings.NewReader(body))
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+	if status := put("/riotfake/faults", `{"notFoundRate":1}`); status != http.StatusOK {
+		t.Fatalf("put faults: status %d", status)
+	}
+	resp, err := http.Get(srv.URL + "/na1/lol/summoner/v4/summoners/" + fakerFanID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("status with faults set over http = %d, want %d", resp.StatusCode, http.StatusNotFound)
+	}
+	if status := put("/riotfake/faults", `{}`); status != http.StatusOK {
+		t.Fatalf("clear faults: status %d", status)
+	}
+
+	if status := put("/riotfake/na1/third-party-code/"+noCodeSetID, `"lss-456"`); status != http.StatusOK {
+		t.Errorf("put code: status %d", status)
+	}
+	code, err := riot.NewHTTPClient(srv.URL+"/{platform}", "", 5*time.Second).ThirdPartyCode(context.Background(), riot.NA1, noCodeSetID)
+	if err != nil || code != "lss-456" {
+		t.Errorf("code set over http = %q, %v", code, err)
+	}
+	if status := put("/riotfake/na1/third-party-code/missing", `"lss-456"`); status != http.StatusNotFound {
+		t.Errorf("put code of an unknown summoner: status %d, want %d", status, http.StatusNotFound)
+	}
+	if status := put("/riotfake/na1/leagues/"+noCodeSetID, `not json`); status != http.StatusBadRequest {
+		t.Errorf("put leagues with a bad body: status %d, want %d", status, http.StatusBadRequest)
+	}
+}
+
+func TestMatches(t *testing.T) {
+	_, srv := newServer(t)
+	tests := []struct {
+		name   string
+		path   string
+		status int
+		body   string
+	}{
+		{"ids by puuid", "/americas/lol/match/v5/matches/by-puuid/" + fakerFanPUUID + "/ids", http.StatusOK, `["NA1_2836021947"]`},
+		{"ids in another region", "/europe/lol/match/v5/matches/by-puuid/" + fakerFanPUUID + "/ids", http.StatusOK, `[]`},
+		{"match", "/americas/lol/match/v5/matches/NA1_2836021947", http.StatusOK, `"matchId":"NA1_2836021947"`},
+		{"unknown match", "/americas/lol/match/v5/matches/NA1_1", http.StatusNotFound, `"status_code":404`},
+		{"account on a platform host", "/na1/riot/account/v1/accounts/by-puuid/" + fakerFanPUUID, http.StatusNotFound, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := http.Get(srv.URL + tt.path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer resp.Body.Close()
+			var body strings.Builder
+			if _, err := io.Copy(&body, resp.Body); err != nil {
+				t.Fatal(err)
+			}
+			if resp.StatusCode != tt.status || !strings.Contains(body.String(), tt.body) {
+				t.Errorf("status %d body %s, want %d containing %s", resp.StatusCode, body.String(), tt.status, tt.body)
+			}
+		})
+	}
+}
@@ -0,0 +1,222 @@
+package main
+
+import (
+	"database/sql"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/render"
+)
+
+// roster roles, the captain role always matches team.captain
+const (
+	roleCaptain    = "captain"
+	roleCoCaptain  = "co-captain"
+	roleMember     = "member"
+	roleSubstitute = "substitute"
+)
+
+var (
+	errNotOnRoster  = newError(KindNotFound, CodeRosterMemberNotFound, "user is not on the team roster")
+	errRoleNotValid = newError(KindValidation, CodeRosterRoleInvalid, "role is not valid")
+	errNotCaptain   = newError(KindForbidden, CodeTeamNotCaptain, "only captain can transfer captaincy")
+	errSameCaptain  = newError(KindValidation, CodeTeamAlreadyCaptain, "already captain")
+)
+
+// RosterMember represents a player on a team roster
+// SummonerID is the member's primary account on the team's region, empty if they have none there
+type RosterMember struct {
+	UserID     int64  `json:"userId"`
+	Username   string `json:"username"`
+	SummonerID string `json:"summonerId,omitempty"`
+	Role       string `json:"role"`
+	JoinedAt   int64  `json:"joinedAt"`
+}
+
+// RosterMemberResponse represents a roster member sent to the client
+type RosterMemberResponse struct {
+	*RosterMember
+}
+
+// Render allows for preprocessing of responses
+func (mr *RosterMemberResponse) Render(w http.ResponseWriter, r *http.Request) error {
+	return nil
+}
+
+// NewRosterListResponse creates a list of roster member responses
+func NewRosterListResponse(members []*RosterMember) []render.Renderer {
+	list := []render.Renderer{}
+	for _, member := range members {
+		list = append(list, &RosterMemberResponse{RosterMember: member})
+	}
+	return list
+}
+
+// RosterRoleRequest represents a request to change the role of a roster member
+type RosterRoleRequest struct {
+	Role        string `json:"role"`
+	ProtectedID int64  `json:"userId"`
+}
+
+// Bind allows for preprocessing of requests
+// captain can only be given through a captaincy transfer
+func (rr *RosterRoleRequest) Bind(r *http.Request) error {
+	switch rr.Role {
+	case roleCoCaptain, roleMember, roleSubstitute:
+	default:
+		v := &ValidationError{}
+		v.Add("role", errRoleNotValid.Error())
+		return v
+	}
+	rr.ProtectedID = protectedID(r)
+	return nil
+}
+
+// TransferCaptainRequest represents a request to hand captaincy to another roster member
+type TransferCaptainRequest struct {
+	NewCaptain  int64 `json:"newCaptain"`
+	ProtectedID int64 `json:"userId"`
+}
+
+// Bind allows for preprocessing of requests
+func (tr *TransferCaptainRequest) Bind(r *http.Request) error {
+	if tr.NewCaptain <= 0 {
+		v := &ValidationError{}
+		v.Add("newCaptain", "must be a user id")
+		return v
+	}
+	tr.ProtectedID = protectedID(r)
+	return nil
+}
+
+// isRosterManager reports whether a role can invite players and manage the roster
+func isRosterManager(role string) bool {
+	return role == roleCaptain || role == roleCoCaptain
+}
+
+func addToRosterAs(p preparer, userID, teamID int64, role string) error {
+	stmt, err := p.Prepare("INSERT INTO roster(teamID,userID,role,joinedAt) VALUES(?,?,?,?)")
+	if err != nil {
+		return err
+	}
+	_, err = stmt.Exec(teamID, userID, role, time.Now().Unix())
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// GetRosterRole returns the role of userID on teamID or errNotOnRoster
+func (m *SQLStore) GetRosterRole(userID, teamID int64) (string, error) {
+	var role string
+	err := m.db.QueryRow("SELECT role FROM roster WHERE teamID=? AND userID=?", teamID, userID).Scan(&role)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return "", errNotOnRoster
+		}
+		return "", err
+	}
+	return role, nil
+}
+
+// SetRosterRole changes the role of a roster member other than the captain
+func (m *SQLStore) SetRosterRole(userID, teamID int64, role string) error {
+	res, err := m.db.Exec("UPDATE roster SET role=? WHERE teamID=? AND userID=? AND role<>?", role, teamID, userID, roleCaptain)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		_, err := m.GetRosterRole(userID, teamID)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// TransferCaptain makes newCaptain the captain of teamID and the old captain a co-captain
+// transferring to the current captain is refused so the team always keeps its captain
+func (m *SQLStore) TransferCaptain(teamID, oldCaptain, newCaptain int64) error {
+	if oldCaptain == newCaptain {
+		return errSameCaptain
+	}
+	return m.withTx("transfer captain", func(tx *sqlTx) error {
+		var role string
+		err := tx.QueryRow("SELECT role FROM roster WHERE teamID=? AND userID=?", teamID, newCaptain).Scan(&role)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				return errNotOnRoster
+			}
+			return err
+		}
+		res, err := tx.Exec("UPDATE team SET captain=? WHERE id=? AND captain=?", newCaptain, teamID, oldCaptain)
+		if err != nil {
+			return err
+		}
+		n, err := res.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if n == 0 {
+			return errNotCaptain
+		}
+		_, err = tx.Exec("UPDATE roster SET role=? WHERE teamID=? AND userID=?", roleCaptain, teamID, newCaptain)
+		if err != nil {
+			return err
+		}
+		_, err = tx.Exec("UPDATE roster SET role=? WHERE teamID=? AND userID=?", roleCoCaptain, teamID, oldCaptain)
+		return err
+	})
+}
+
+// KickFromRoster removes userID from teamID and records who removed them and why
+func (m *SQLStore) KickFromRoster(userID, teamID, removedBy int64, reason string) error {
+	return m.withTx("kick from roster", func(tx *sqlTx) error {
+		res, err := tx.Exec("DELETE FROM roster WHERE teamID=? AND userID=? AND role<>?", teamID, userID, roleCaptain)
+		if err != nil {
+			return err
+		}
+		n, err := res.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if n == 0 {
+			return errNotOnRoster
+		}
+		_, err = tx.Exec("INSERT INTO roster_removal(teamId,userId,removedBy,reason,removedAt) VALUES(?,?,?,?,?)", teamID, userID, removedBy, reason, time.Now().Unix())
+		return err
+	})
+}
+
+// GetTeamRoster returns a page of the roster of teamID, captain and co-captains first,
+// then by join time and user id so members who joined in the same second keep their place across pages
+func (m *SQLStore) GetTeamRoster(teamID int64, limit, offset int) ([]*RosterMember, error) {
+	var members []*RosterMember
+	rows, err := m.db.Query(`SELECT account.id, account.username, COALESCE(linked_account.summonerId, ''), roster.role, roster.joinedAt FROM roster
+		INNER JOIN account ON account.id=roster.userID
+		INNER JOIN team ON team.id=roster.teamID
+		LEFT JOIN linked_account ON linked_account.userId=roster.userID AND linked_account.region=team.region AND linked_account.isPrimary=?
+		WHERE roster.teamID = ?
+		ORDER BY CASE roster.role WHEN ? THEN 0 WHEN ? THEN 1 ELSE 2 END, roster.joinedAt, roster.userID LIMIT ? OFFSET ?`, true, teamID, roleCaptain, roleCoCaptain, limit, offset)
+	if err != nil {
+		return members, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var member RosterMember
+		err := rows.Scan(&member.UserID, &member.Username, &member.SummonerID, &member.Role, &member.JoinedAt)
+		if err != nil {
+			return members, err
+		}
+		members = append(members, &member)
+	}
+	err = rows.Err()
+	if err != nil {
+		return members, err
+	}
+	return members, nil
+}
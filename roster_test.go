@@ -0,0 +1,92 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestTransferCaptain(t *testing.T) {
+	forEachStore(t, func(t *testing.T, store Store) {
+		captain := newTestUser(t, store, "captain")
+		player := newTestUser(t, store, "player")
+		outsider := newTestUser(t, store, "outsider")
+		team := newTestTeam(t, store, captain)
+		link := &TeamJoinLink{Team: team.ID}
+		if err := store.NewTeamJoinLink(link, captain); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := store.RedeemTeamJoinLink(link.Code, player); err != nil {
+			t.Fatal(err)
+		}
+
+		tests := []struct {
+			name       string
+			oldCaptain int64
+			newCaptain int64
+			err        error
+		}{
+			{"to the captain", captain, captain, errSameCaptain},
+			{"to a player not on the roster", captain, outsider, errNotOnRoster},
+			{"by a player", player, player, errSameCaptain},
+			{"by someone other than the captain", outsider, player, errNotCaptain},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				if err := store.TransferCaptain(team.ID, tt.oldCaptain, tt.newCaptain); !errors.Is(err, tt.err) {
+					t.Errorf("transfer: %v, want %v", err, tt.err)
+				}
+			})
+		}
+		if role, err := store.GetRosterRole(captain, team.ID); err != nil || role != roleCaptain {
+			t.Fatalf("captain role after refused transfers = %q, %v", role, err)
+		}
+
+		if err := store.TransferCaptain(team.ID, captain, player); err != nil {
+			t.Fatal(err)
+		}
+		got, err := store.GetTeam(team.ID)
+		if err != nil || got.Captain != player {
+			t.Errorf("team after transfer = %+v, %v", got, err)
+		}
+		if role, err := store.GetRosterRole(player, team.ID); err != nil || role != roleCaptain {
+			t.Errorf("new captain role = %q, %v", role, err)
+		}
+		if role, err := store.GetRosterRole(captain, team.ID); err != nil || role != roleCoCaptain {
+			t.Errorf("old captain role = %q, %v", role, err)
+		}
+	})
+}
+
+func TestGetTeamRosterPages(t *testing.T) {
+	forEachStore(t, func(t *testing.T, store Store) {
+		captain := newTestUser(t, store, "captain")
+		team := newTestTeam(t, store, captain)
+		link := &TeamJoinLink{Team: team.ID}
+		if err := store.NewTeamJoinLink(link, captain); err != nil {
+			t.Fatal(err)
+		}
+		want := []int64{captain}
+		for _, name := range []string{"delta", "alpha", "charlie", "bravo", "echo"} {
+			id := newTestUser(t, store, name)
+			if _, err := store.RedeemTeamJoinLink(link.Code, id); err != nil {
+				t.Fatal(err)
+			}
+			want = append(want, id)
+		}
+
+		var got []int64
+		for offset := 0; offset < len(want)+2; offset += 2 {
+			page, err := store.GetTeamRoster(team.ID, 2, offset)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, member := range page {
+				got = append(got, member.UserID)
+			}
+		}
+		if fmt.Sprint(got) != fmt.Sprint(want) {
+			t.Errorf("roster pages = %v, want %v", got, want)
+		}
+	})
+}
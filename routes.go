@@ -10,19 +10,24 @@ import (
 )
 
 // Routes is the index of all api routes where paths are mounted
-func Routes() *chi.Mux {
+// errors are sent as problem details by ProblemJSON
+func (s *Server) Routes() *chi.Mux {
 	r := chi.NewRouter()
 	r.Use(
 		render.SetContentType(render.ContentTypeJSON),
+		ProblemJSON,
+		WithRules(s.rules),
 		middleware.DefaultCompress,
 		middleware.Logger,
 		middleware.RedirectSlashes,
 		middleware.Recoverer,
-		middleware.Timeout(60*time.Second))
+		middleware.Timeout(60*time.Second),
+		s.Authenticate)
 	r.Get("/", func(w http.ResponseWriter, r *http.Request) {
 		w.Write([]byte("api running"))
 	})
-	r.Mount("/user", UserRoutes())
-	r.Mount("/team", TeamRoutes())
+	r.Mount("/auth", s.AuthRoutes())
+	r.Mount("/user", s.UserRoutes())
+	r.Mount("/team", s.TeamRoutes())
 	return r
 }
@@ -6,20 +6,194 @@ import (
 	"log"
 	"net/http"
 	"os"
+	"strconv"
+	"time"
 
-	_ "github.com/go-sql-driver/mysql"
+	"github.com/anthonyrouseau/lss-api/riot"
+	"golang.org/x/crypto/bcrypt"
 )
 
-var db *sql.DB
+// Server holds the stores, keys and rules used by the api handlers
+type Server struct {
+	users       UserStore
+	teams       TeamStore
+	invites     InviteStore
+	riot        riot.Client
+	tokenSecret []byte
+	rules       *Rules
+}
+
+// NewServer creates a Server that looks up summoners with riotClient,
+// signs session tokens with tokenSecret and checks requests against rules
+func NewServer(users UserStore, teams TeamStore, invites InviteStore, riotClient riot.Client, tokenSecret []byte, rules *Rules) *Server {
+	return &Server{
+		users:       users,
+		teams:       teams,
+		invites:     invites,
+		riot:        riotClient,
+		tokenSecret: tokenSecret,
+		rules:       rules,
+	}
+}
 
 func main() {
-	var err error
-	db, err = sql.Open("mysql", os.Getenv("testdb"))
+	store, closeStore, err := openStore()
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer closeStore()
+	rules := NewRules()
+	if err := configurePasswordPolicy(rules.Passwords); err != nil {
+		log.Fatal(err)
+	}
+	if err := configureValidation(rules); err != nil {
+		log.Fatal(err)
+	}
+	if len(os.Args) > 1 && os.Args[1] == "upgrade-passwords" {
+		upgrader, ok := store.(interface {
+			UpgradePasswords(policy *PasswordPolicy) (int, error)
+		})
+		if !ok {
+			log.Fatal("upgrade-passwords needs a database store")
+		}
+		n, err := upgrader.UpgradePasswords(rules.Passwords)
+		if err != nil {
+			log.Fatal(err)
+		}
+		fmt.Printf("hashed %d plaintext passwords\n", n)
+		return
+	}
+	if sqlStore, ok := store.(*SQLStore); ok {
+		if len(os.Args) > 1 && os.Args[1] == "migrate" {
+			if err := runMigrateCommand(sqlStore, os.Args[2:]); err != nil {
+				log.Fatal(err)
+			}
+			return
+		}
+		if err := sqlStore.CheckSchema(); err != nil {
+			log.Fatal(err)
+		}
+	}
+	tokenSecret := []byte(os.Getenv("tokensecret"))
+	if len(tokenSecret) == 0 {
+		log.Fatal("tokensecret must be set")
+	}
+	riotClient, err := openRiotClient()
 	if err != nil {
 		log.Fatal(err)
 	}
-	defer db.Close()
-	r := Routes()
+	syncInterval, staleAfter, err := rankSyncSettings()
+	if err != nil {
+		log.Fatal(err)
+	}
+	s := NewServer(store, store, store, riotClient, tokenSecret, rules)
+	stop := make(chan struct{})
+	defer close(stop)
+	go s.sweepTeamInvites(10*time.Minute, stop)
+	go s.syncRanks(syncInterval, staleAfter, stop)
+	r := s.Routes()
 	fmt.Println("server starting up")
 	http.ListenAndServe(":1337", r)
 }
+
+// openStore opens the backend named by the store env variable
+// "memory" keeps everything in process, "sqlite" uses the database file at testdb,
+// "postgres" and the default "mysql" connect to testdb
+func openStore() (Store, func(), error) {
+	switch backend := os.Getenv("store"); backend {
+	case "memory":
+		return NewMemoryStore(), func() {}, nil
+	case "sqlite":
+		db, err := openSQLite(os.Getenv("testdb"))
+		if err != nil {
+			return nil, nil, err
+		}
+		return NewSQLStore(db, sqliteDialect), func() { db.Close() }, nil
+	case "postgres":
+		db, err := sql.Open(postgresDialect.driver, os.Getenv("testdb"))
+		if err != nil {
+			return nil, nil, err
+		}
+		return NewSQLStore(db, postgresDialect), func() { db.Close() }, nil
+	case "", "mysql":
+		db, err := sql.Open(mysqlDialect.driver, os.Getenv("testdb"))
+		if err != nil {
+			return nil, nil, err
+		}
+		return NewSQLStore(db, mysqlDialect), func() { db.Close() }, nil
+	default:
+		return nil, nil, fmt.Errorf("store %q not supported", backend)
+	}
+}
+
+// openRiotClient creates a Riot API client from the environment
+// riotbaseurl overrides the api host and riottimeout the time allowed per request
+func openRiotClient() (riot.Client, error) {
+	timeout := 10 * time.Second
+	if v := os.Getenv("riottimeout"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return nil, fmt.Errorf("riottimeout: %v", err)
+		}
+		timeout = d
+	}
+	return riot.NewHTTPClient(os.Getenv("riotbaseurl"), os.Getenv("riotapikey"), timeout), nil
+}
+
+// rankSyncSettings reads how often linked accounts are synced from riot from the environment
+// ranksyncinterval is the time between batches and rankstaleafter the age of a sync that is refreshed
+func rankSyncSettings() (time.Duration, time.Duration, error) {
+	interval, staleAfter := defaultRankSyncInterval, defaultRankStaleAfter
+	if v := os.Getenv("ranksyncinterval"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d <= 0 {
+			return 0, 0, fmt.Errorf("ranksyncinterval: %q is not a positive duration", v)
+		}
+		interval = d
+	}
+	if v := os.Getenv("rankstaleafter"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d < 0 {
+			return 0, 0, fmt.Errorf("rankstaleafter: %q is not a duration", v)
+		}
+		staleAfter = d
+	}
+	return interval, staleAfter, nil
+}
+
+// configurePasswordPolicy overrides the defaults of policy from the environment
+func configurePasswordPolicy(policy *PasswordPolicy) error {
+	if v := os.Getenv("passwordminlength"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return fmt.Errorf("passwordminlength: %v", err)
+		}
+		policy.MinLength = n
+	}
+	if v := os.Getenv("bcryptcost"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return fmt.Errorf("bcryptcost: %v", err)
+		}
+		if n < bcrypt.MinCost || n > bcrypt.MaxCost {
+			return fmt.Errorf("bcryptcost must be between %d and %d", bcrypt.MinCost, bcrypt.MaxCost)
+		}
+		policy.Cost = n
+	}
+	if path := os.Getenv("passwordblocklist"); path != "" {
+		if err := policy.LoadBlocklist(path); err != nil {
+			return fmt.Errorf("passwordblocklist: %v", err)
+		}
+	}
+	return nil
+}
+
+// configureValidation loads the words names may not contain into rules from the environment
+func configureValidation(rules *Rules) error {
+	if path := os.Getenv("profanitylist"); path != "" {
+		if err := rules.Profanity.Load(path); err != nil {
+			return fmt.Errorf("profanitylist: %v", err)
+		}
+	}
+	return nil
+}
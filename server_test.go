@@ -0,0 +1,79 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/anthonyrouseau/lss-api/riot"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// testServer serves the api from a MemoryStore so handlers can be tested without a database
+type testServer struct {
+	t      *testing.T
+	store  *MemoryStore
+	riot   *riot.Stub
+	router http.Handler
+}
+
+func newTestServer(t *testing.T) *testServer {
+	rules := NewRules()
+	rules.Passwords.Cost = bcrypt.MinCost
+	store := NewMemoryStore()
+	stub := riot.NewStub()
+	s := NewServer(store, store, store, stub, []byte("test secret"), rules)
+	return &testServer{t: t, store: store, riot: stub, router: s.Routes()}
+}
+
+// do sends body as json with token as the bearer token if it isn't empty,
+// decodes the response into out if it isn't nil and returns the status
+func (ts *testServer) do(method, path, token string, body, out interface{}) int {
+	ts.t.Helper()
+	var buf bytes.Buffer
+	if body != nil {
+		if err := json.NewEncoder(&buf).Encode(body); err != nil {
+			ts.t.Fatal(err)
+		}
+	}
+	req := httptest.NewRequest(method, path, &buf)
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	w := httptest.NewRecorder()
+	ts.router.ServeHTTP(w, req)
+	if out != nil && w.Code < http.StatusBadRequest {
+		if err := json.Unmarshal(w.Body.Bytes(), out); err != nil {
+			ts.t.Fatalf("%s %s: decoding %q: %v", method, path, w.Body.String(), err)
+		}
+	}
+	return w.Code
+}
+
+// signup creates a user named username and logs it in
+func (ts *testServer) signup(username string) (int64, *TokenResponse) {
+	ts.t.Helper()
+	password := "correct horse battery"
+	user := map[string]string{"username": username, "password": password, "email": username + "@example.com"}
+	if status := ts.do("POST", "/user", "", user, nil); status != http.StatusOK {
+		ts.t.Fatalf("signup %s: status %d", username, status)
+	}
+	var tokens TokenResponse
+	login := map[string]string{"username": username, "password": password}
+	if status := ts.do("POST", "/auth/login", "", login, &tokens); status != http.StatusOK {
+		ts.t.Fatalf("login %s: status %d", username, status)
+	}
+	return tokens.UserID, &tokens
+}
+
+// createTeam creates a team captained by the user of token
+func (ts *testServer) createTeam(token, name string) *Team {
+	ts.t.Helper()
+	var team Team
+	if status := ts.do("POST", "/team", token, map[string]string{"name": name}, &team); status != http.StatusOK {
+		ts.t.Fatalf("create team %s: status %d", name, status)
+	}
+	return &team
+}
@@ -0,0 +1,218 @@
+package main
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-chi/render"
+)
+
+// refreshTTL is how long a session can go without being refreshed
+const refreshTTL = 30 * 24 * time.Hour
+
+var (
+	errSessionInvalid = newError(KindForbidden, CodeSessionInvalid, "refresh token is invalid or expired")
+	errSessionReused  = newError(KindForbidden, CodeSessionReused, "refresh token was already used, session revoked")
+	errSessionMissing = newError(KindNotFound, CodeSessionNotFound, "session not found")
+)
+
+// Session represents a logged in device in the database
+// each refresh rotates the token hash but keeps the session ID
+type Session struct {
+	ID         string `json:"sessionId"`
+	UserID     int64  `json:"-"`
+	UserAgent  string `json:"userAgent,omitempty"`
+	CreatedAt  int64  `json:"createdAt"`
+	LastUsedAt int64  `json:"lastUsedAt"`
+	ExpiresAt  int64  `json:"expiresAt"`
+}
+
+// SessionResponse represents a session sent to the client
+type SessionResponse struct {
+	*Session
+	Current bool `json:"current"`
+}
+
+// NewSessionResponse creates a session response, marking the session used by the request
+func NewSessionResponse(session *Session, currentID string) *SessionResponse {
+	return &SessionResponse{Session: session, Current: session.ID == currentID}
+}
+
+// Render allows for preprocessing of session response
+func (sr *SessionResponse) Render(w http.ResponseWriter, r *http.Request) error {
+	return nil
+}
+
+// NewSessionListResponse creates a list of session responses
+func NewSessionListResponse(sessions []*Session, currentID string) []render.Renderer {
+	list := []render.Renderer{}
+	for _, session := range sessions {
+		list = append(list, NewSessionResponse(session, currentID))
+	}
+	return list
+}
+
+func randomToken(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+func hashToken(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+// splitRefreshToken splits a refresh token into its session ID and secret
+func splitRefreshToken(token string) (string, string, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return "", "", errSessionInvalid
+	}
+	return parts[0], parts[1], nil
+}
+
+// NewSession starts a session for userID and returns it with its first refresh token
+func (m *SQLStore) NewSession(userID int64, userAgent string) (*Session, string, error) {
+	id, err := randomToken(16)
+	if err != nil {
+		return nil, "", err
+	}
+	secret, err := randomToken(32)
+	if err != nil {
+		return nil, "", err
+	}
+	now := time.Now()
+	session := &Session{
+		ID:         id,
+		UserID:     userID,
+		UserAgent:  userAgent,
+		CreatedAt:  now.Unix(),
+		LastUsedAt: now.Unix(),
+		ExpiresAt:  now.Add(refreshTTL).Unix(),
+	}
+	stmt, err := m.db.Prepare("INSERT INTO session(id,userId,tokenHash,userAgent,createdAt,lastUsedAt,expiresAt) VALUES(?,?,?,?,?,?,?)")
+	if err != nil {
+		return nil, "", err
+	}
+	_, err = stmt.Exec(session.ID, session.UserID, hashToken(secret), session.UserAgent, session.CreatedAt, session.LastUsedAt, session.ExpiresAt)
+	if err != nil {
+		return nil, "", err
+	}
+	return session, session.ID + "." + secret, nil
+}
+
+// RotateSession exchanges a refresh token for a new one in the same session
+// presenting a token that was already rotated out revokes the session
+func (m *SQLStore) RotateSession(refreshToken string) (*Session, string, error) {
+	id, secret, err := splitRefreshToken(refreshToken)
+	if err != nil {
+		return nil, "", err
+	}
+	newSecret, err := randomToken(32)
+	if err != nil {
+		return nil, "", err
+	}
+	now := time.Now()
+	res, err := m.db.Exec("UPDATE session SET tokenHash=?, lastUsedAt=?, expiresAt=? WHERE id=? AND tokenHash=? AND revokedAt IS NULL AND expiresAt > ?",
+		hashToken(newSecret), now.Unix(), now.Add(refreshTTL).Unix(), id, hashToken(secret), now.Unix())
+	if err != nil {
+		return nil, "", err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return nil, "", err
+	}
+	if n == 0 {
+		var revokedAt sql.NullInt64
+		var expiresAt int64
+		err := m.db.QueryRow("SELECT revokedAt, expiresAt FROM session WHERE id=?", id).Scan(&revokedAt, &expiresAt)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				return nil, "", errSessionInvalid
+			}
+			return nil, "", err
+		}
+		if revokedAt.Valid || expiresAt <= now.Unix() {
+			return nil, "", errSessionInvalid
+		}
+		_, err = m.db.Exec("UPDATE session SET revokedAt=? WHERE id=?", now.Unix(), id)
+		if err != nil {
+			return nil, "", err
+		}
+		return nil, "", errSessionReused
+	}
+	session := &Session{ID: id}
+	err = m.db.QueryRow("SELECT userId, userAgent, createdAt, lastUsedAt, expiresAt FROM session WHERE id=?", id).
+		Scan(&session.UserID, &session.UserAgent, &session.CreatedAt, &session.LastUsedAt, &session.ExpiresAt)
+	if err != nil {
+		return nil, "", err
+	}
+	return session, id + "." + newSecret, nil
+}
+
+// SessionActive reports whether a session exists for userID and has not been revoked or expired
+func (m *SQLStore) SessionActive(sessionID string, userID int64) (bool, error) {
+	var id string
+	err := m.db.QueryRow("SELECT id FROM session WHERE id=? AND userId=? AND revokedAt IS NULL AND expiresAt > ?", sessionID, userID, time.Now().Unix()).Scan(&id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+// RevokeSession revokes one session of userID or returns errSessionMissing
+func (m *SQLStore) RevokeSession(sessionID string, userID int64) error {
+	res, err := m.db.Exec("UPDATE session SET revokedAt=? WHERE id=? AND userId=? AND revokedAt IS NULL", time.Now().Unix(), sessionID, userID)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return errSessionMissing
+	}
+	return nil
+}
+
+// RevokeOtherSessions revokes every active session of userID except keepID
+func (m *SQLStore) RevokeOtherSessions(userID int64, keepID string) error {
+	_, err := m.db.Exec("UPDATE session SET revokedAt=? WHERE userId=? AND id<>? AND revokedAt IS NULL", time.Now().Unix(), userID, keepID)
+	return err
+}
+
+// GetUserSessions returns the active sessions of userID, most recently used first
+func (m *SQLStore) GetUserSessions(userID int64) ([]*Session, error) {
+	var sessions []*Session
+	rows, err := m.db.Query("SELECT id, userId, userAgent, createdAt, lastUsedAt, expiresAt FROM session WHERE userId=? AND revokedAt IS NULL AND expiresAt > ? ORDER BY lastUsedAt DESC", userID, time.Now().Unix())
+	if err != nil {
+		return sessions, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var session Session
+		err := rows.Scan(&session.ID, &session.UserID, &session.UserAgent, &session.CreatedAt, &session.LastUsedAt, &session.ExpiresAt)
+		if err != nil {
+			return sessions, err
+		}
+		sessions = append(sessions, &session)
+	}
+	err = rows.Err()
+	if err != nil {
+		return sessions, err
+	}
+	return sessions, nil
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"strconv"
+	"strings"
+
+	"github.com/mattn/go-sqlite3"
+)
+
+// sqliteDialect is used by SQLStores opened with the sqlite3 driver
+// usernames are declared COLLATE NOCASE so they compare ignoring case
+var sqliteDialect = &dialect{
+	driver:           "sqlite3",
+	startsWith:       "LIKE ? || '%'",
+	usernameIs:       "username = ?",
+	classify:         classifySQLiteError,
+	transactionalDDL: true,
+	// sqlite 3.34 has no DROP COLUMN
+	rebuildsTables: true,
+}
+
+// openSQLite opens the database file at dsn with foreign keys enforced
+// sqlite allows one writer at a time so the pool is limited to a single connection
+func openSQLite(dsn string) (*sql.DB, error) {
+	if dsn == "" {
+		dsn = "lss.db"
+	}
+	sep := "?"
+	if strings.Contains(dsn, "?") {
+		sep = "&"
+	}
+	db, err := sql.Open(sqliteDialect.driver, dsn+sep+"_foreign_keys=1")
+	if err != nil {
+		return nil, err
+	}
+	db.SetMaxOpenConns(1)
+	return db, nil
+}
+
+// classifySQLiteError converts a sqlite3.Error to a *DBError
+func classifySQLiteError(err error) *DBError {
+	var e sqlite3.Error
+	if !errors.As(err, &e) {
+		return nil
+	}
+	kind := KindInternal
+	switch e.ExtendedCode {
+	case sqlite3.ErrConstraintUnique, sqlite3.ErrConstraintPrimaryKey:
+		kind = KindConflict
+	case sqlite3.ErrConstraintForeignKey, sqlite3.ErrConstraintNotNull, sqlite3.ErrConstraintCheck:
+		kind = KindValidation
+	}
+	if e.Code == sqlite3.ErrBusy || e.Code == sqlite3.ErrLocked {
+		kind = KindUnavailable
+	}
+	return &DBError{Kind: kind, Code: strconv.Itoa(int(e.ExtendedCode)), Message: e.Error(), Err: e}
+}
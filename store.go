@@ -0,0 +1,73 @@
+package main
+
+import "time"
+
+// UserStore persists accounts, their login sessions and linked game accounts
+type UserStore interface {
+	NewUser(user *User) (int64, error)
+	SearchUsername(searchValue string, offset int) ([]*User, error)
+	GetCredentials(username string) (int64, string, error)
+	SetPassword(userID int64, hash string) error
+	NewSession(userID int64, userAgent string) (*Session, string, error)
+	RotateSession(refreshToken string) (*Session, string, error)
+	SessionActive(sessionID string, userID int64) (bool, error)
+	RevokeSession(sessionID string, userID int64) error
+	RevokeOtherSessions(userID int64, keepID string) error
+	GetUserSessions(userID int64) ([]*Session, error)
+	GetSummonerOwner(puuid string) (int64, error)
+	SetSummonerVerification(v *SummonerVerification) error
+	GetSummonerVerification(userID int64) (*SummonerVerification, error)
+	LinkAccount(v *SummonerVerification) (*LinkedAccount, error)
+	GetLinkedAccounts(userID int64) ([]*LinkedAccount, error)
+	SetPrimaryAccount(userID, accountID int64) error
+	UnlinkAccount(userID, accountID int64) error
+	UnlinkAllAccounts(userID int64) error
+	GetStaleAccounts(before int64, limit int) ([]*LinkedAccount, error)
+	SaveAccountSync(sync *AccountSync) error
+	GetCurrentRanks(userIDs []int64) ([]*RankSnapshot, error)
+	GetRankHistory(userID int64, region, queue string, limit int) ([]*RankSnapshot, error)
+}
+
+// TeamStore persists teams and their rosters
+type TeamStore interface {
+	CreateTeam(team *Team) error
+	GetTeam(teamID int64) (*Team, error)
+	SearchTeamName(searchValue string, offset int) ([]*Team, error)
+	GetUserTeams(userID string) ([]*Team, error)
+	SetTeamAcceptsRequests(teamID int64, accepts bool) error
+	DisbandTeam(teamID int64) error
+	IsCaptain(userID, teamID int64) (bool, error)
+	EditRoster(action string, userID, teamID int64) error
+	GetRosterRole(userID, teamID int64) (string, error)
+	SetRosterRole(userID, teamID int64, role string) error
+	TransferCaptain(teamID, oldCaptain, newCaptain int64) error
+	KickFromRoster(userID, teamID, removedBy int64, reason string) error
+	GetTeamRoster(teamID int64, limit, offset int) ([]*RosterMember, error)
+	GetRosterRanks(teamIDs []int64) (map[int64][]*RankSnapshot, error)
+}
+
+// InviteStore persists the ways a player can join a team:
+// invites from the team, join links and join requests from the player
+type InviteStore interface {
+	NewTeamInvite(invite *TeamInvite) error
+	GetTeamInvite(inviteID int64) (*TeamInvite, error)
+	GetUserTeamInvites(userID string) ([]*TeamInvite, error)
+	GetTeamInvites(teamID int64) ([]*TeamInvite, error)
+	AcceptTeamInvite(invite *TeamInvite) error
+	DeleteTeamInvite(inviteID int64) error
+	ExpireTeamInvites(now time.Time) error
+	NewTeamJoinLink(link *TeamJoinLink, createdBy int64) error
+	GetTeamJoinLinks(teamID int64) ([]*TeamJoinLink, error)
+	RevokeTeamJoinLink(code string, teamID int64) error
+	RedeemTeamJoinLink(code string, userID int64) (int64, error)
+	NewTeamJoinRequest(req *TeamJoinRequest) error
+	GetTeamJoinRequests(teamID int64) ([]*TeamJoinRequest, error)
+	DecideTeamJoinRequest(requestID, teamID int64, approve bool) (*TeamJoinRequest, error)
+}
+
+// Store is a backend that provides every store the server needs
+type Store interface {
+	UserStore
+	TeamStore
+	InviteStore
+}
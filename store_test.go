@@ -0,0 +1,53 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"testing"
+)
+
+// forEachStore runs test against a MemoryStore and a migrated sqlite SQLStore
+// so both backends are held to the same behaviour
+func forEachStore(t *testing.T, test func(t *testing.T, store Store)) {
+	t.Run("memory", func(t *testing.T) {
+		test(t, NewMemoryStore())
+	})
+	t.Run("sqlite", func(t *testing.T) {
+		test(t, newTestSQLStore(t))
+	})
+}
+
+// newTestSQLStore opens a sqlite database in a temporary directory and migrates it up
+func newTestSQLStore(t *testing.T) *SQLStore {
+	t.Helper()
+	db, err := openSQLite(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	store := NewSQLStore(db, sqliteDialect)
+	if _, err := store.MigrateUp(); err != nil {
+		t.Fatalf("migrate up: %v", err)
+	}
+	return store
+}
+
+// newTestUser creates a user named username in store and returns its id
+func newTestUser(t *testing.T, store Store, username string) int64 {
+	t.Helper()
+	id, err := store.NewUser(&User{Username: username, Password: "hash", Email: username + "@example.com"})
+	if err != nil {
+		t.Fatalf("new user %s: %v", username, err)
+	}
+	return id
+}
+
+// newTestTeam creates a team on na1 captained by captain and returns it
+func newTestTeam(t *testing.T, store Store, captain int64) *Team {
+	t.Helper()
+	team := &Team{Name: fmt.Sprintf("Team %d", captain), Captain: captain, Region: "na1"}
+	if err := store.CreateTeam(team); err != nil {
+		t.Fatalf("create team: %v", err)
+	}
+	return team
+}
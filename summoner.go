@@ -0,0 +1,184 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/anthonyrouseau/lss-api/riot"
+)
+
+// summonerVerificationTTL is how long a user has to set the code in the client
+const summonerVerificationTTL = 30 * time.Minute
+
+// SummonerVerification is a pending link of a summoner to a user, completed once
+// the summoner sets Code as its third party code
+type SummonerVerification struct {
+	UserID     int64  `json:"-"`
+	RiotID     string `json:"riotId"`
+	PUUID      string `json:"puuid"`
+	SummonerID string `json:"summonerId"`
+	Region     string `json:"region"`
+	Code       string `json:"code"`
+	CreatedAt  int64  `json:"createdAt"`
+	ExpiresAt  int64  `json:"expiresAt"`
+}
+
+var (
+	errSummonerAlreadyLinked = newError(KindConflict, CodeSummonerAlreadyLinked, "summoner is already linked to another account")
+	errSummonerOtherRegion   = newError(KindConflict, CodeSummonerAlreadyLinked, "summoner is already linked on another region")
+	errSummonerNotLinked     = newError(KindNotFound, CodeSummonerNotLinked, "no summoner is linked to this account")
+	errVerificationNotFound  = newError(KindNotFound, CodeVerificationNotFound, "no summoner verification is pending")
+	errVerificationExpired   = newError(KindConflict, CodeVerificationExpired, "summoner verification has expired, start again")
+)
+
+// SummonerLinkRequest represents a request to start linking a summoner
+type SummonerLinkRequest struct {
+	// RiotID is the account to link written gameName#tagLine, on the platform in Region
+	RiotID      string        `json:"riotId"`
+	Region      string        `json:"region"`
+	ProtectedID int64         `json:"userId"`
+	Platform    riot.Platform `json:"-"`
+	riotID      riot.RiotID
+}
+
+// Bind allows for preprocessing of requests
+func (lr *SummonerLinkRequest) Bind(r *http.Request) error {
+	v := &ValidationError{}
+	platform, riotID := bindRiotAccount(lr.Region, lr.RiotID, v)
+	if lr.RiotID == "" {
+		v.Add("riotId", "is required")
+	}
+	lr.Platform = platform
+	lr.riotID = riotID
+	lr.ProtectedID = protectedID(r)
+	return v.Err()
+}
+
+// bindRiotAccount parses the region and Riot ID fields of a request, reporting
+// field errors to v, an empty region is na1 and an empty riotID is not parsed
+func bindRiotAccount(region, riotID string, v *ValidationError) (riot.Platform, riot.RiotID) {
+	platform := riot.NA1
+	if region != "" {
+		p, err := riot.ParsePlatform(region)
+		if err != nil {
+			v.Add("region", "is not a riot platform")
+		}
+		platform = p
+	}
+	var id riot.RiotID
+	if riotID != "" {
+		parsed, err := riot.ParseRiotID(riotID)
+		if err != nil {
+			v.Add("riotId", "must be written gameName#tagLine")
+		}
+		id = parsed
+	}
+	return platform, id
+}
+
+// SummonerVerificationResponse represents a pending verification sent to the client
+type SummonerVerificationResponse struct {
+	*SummonerVerification
+}
+
+// NewSummonerVerificationResponse creates a response from a pending verification
+func NewSummonerVerificationResponse(v *SummonerVerification) *SummonerVerificationResponse {
+	return &SummonerVerificationResponse{SummonerVerification: v}
+}
+
+// Render allows for preprocessing of responses
+func (vr *SummonerVerificationResponse) Render(w http.ResponseWriter, r *http.Request) error {
+	return nil
+}
+
+// verificationCode returns a random code short enough to type into the client
+func verificationCode() (string, error) {
+	b := make([]byte, 4)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return "LSS-" + strings.ToUpper(hex.EncodeToString(b)), nil
+}
+
+// resolveSummoner looks up the summoner of riotID on platform
+func (s *Server) resolveSummoner(ctx context.Context, platform riot.Platform, riotID riot.RiotID) (*riot.Summoner, error) {
+	account, err := s.riot.AccountByRiotID(ctx, platform, riotID)
+	if err != nil {
+		if errors.Is(err, riot.ErrNotFound) {
+			return nil, errSummonerNotFound
+		}
+		return nil, err
+	}
+	summoner, err := s.riot.SummonerByPUUID(ctx, platform, account.PUUID)
+	if err != nil {
+		if errors.Is(err, riot.ErrNotFound) {
+			return nil, errSummonerNotFound
+		}
+		return nil, err
+	}
+	summoner.PUUID = account.PUUID
+	return summoner, nil
+}
+
+// checkThirdPartyCode returns errSummonerCodeMismatch unless the summoner has set code
+func (s *Server) checkThirdPartyCode(ctx context.Context, platform riot.Platform, summonerID, code string) error {
+	userCode, err := s.riot.ThirdPartyCode(ctx, platform, summonerID)
+	if err != nil {
+		if errors.Is(err, riot.ErrNotFound) {
+			return errSummonerCodeMismatch
+		}
+		return err
+	}
+	if userCode != code {
+		return errSummonerCodeMismatch
+	}
+	return nil
+}
+
+// SetSummonerVerification replaces the pending verification of v.UserID
+func (m *SQLStore) SetSummonerVerification(v *SummonerVerification) error {
+	return m.withTx("set summoner verification", func(tx *sqlTx) error {
+		_, err := tx.Exec("DELETE FROM summoner_verification WHERE userId=?", v.UserID)
+		if err != nil {
+			return err
+		}
+		_, err = tx.Exec("INSERT INTO summoner_verification(userId,riotId,puuid,summonerId,region,code,createdAt,expiresAt) VALUES(?,?,?,?,?,?,?,?)",
+			v.UserID, v.RiotID, v.PUUID, v.SummonerID, v.Region, v.Code, v.CreatedAt, v.ExpiresAt)
+		return err
+	})
+}
+
+// GetSummonerVerification returns the pending verification of userID or errVerificationNotFound
+func (m *SQLStore) GetSummonerVerification(userID int64) (*SummonerVerification, error) {
+	v := &SummonerVerification{UserID: userID}
+	err := m.db.QueryRow("SELECT riotId, puuid, summonerId, region, code, createdAt, expiresAt FROM summoner_verification WHERE userId=?", userID).
+		Scan(&v.RiotID, &v.PUUID, &v.SummonerID, &v.Region, &v.Code, &v.CreatedAt, &v.ExpiresAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, errVerificationNotFound
+		}
+		return nil, err
+	}
+	return v, nil
+}
+
+// checkSummonerFree returns errSummonerAlreadyLinked if puuid is linked to a user other than userID
+func (s *Server) checkSummonerFree(puuid string, userID int64) error {
+	owner, err := s.users.GetSummonerOwner(puuid)
+	if err == errUserNotFound {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if owner != userID {
+		return errSummonerAlreadyLinked
+	}
+	return nil
+}
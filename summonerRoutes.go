@@ -0,0 +1,86 @@
+package main
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/anthonyrouseau/lss-api/riot"
+	"github.com/go-chi/render"
+)
+
+// StartSummonerLink starts linking a summoner to the authenticated user, the response
+// has the code to set as the summoner's third party code before verifying
+func (s *Server) StartSummonerLink(w http.ResponseWriter, r *http.Request) {
+	data := &SummonerLinkRequest{}
+	if err := render.Bind(r, data); err != nil {
+		render.Render(w, r, ErrBadRequest(err))
+		return
+	}
+	summoner, err := s.resolveSummoner(r.Context(), data.Platform, data.riotID)
+	if err != nil {
+		render.Render(w, r, ErrRiot(err))
+		return
+	}
+	if err := s.checkSummonerFree(summoner.PUUID, data.ProtectedID); err != nil {
+		render.Render(w, r, ErrDB(err))
+		return
+	}
+	code, err := verificationCode()
+	if err != nil {
+		render.Render(w, r, ErrRender(err))
+		return
+	}
+	now := time.Now()
+	v := &SummonerVerification{
+		UserID:     data.ProtectedID,
+		RiotID:     data.riotID.String(),
+		PUUID:      summoner.PUUID,
+		SummonerID: summoner.ID,
+		Region:     string(data.Platform),
+		Code:       code,
+		CreatedAt:  now.Unix(),
+		ExpiresAt:  now.Add(summonerVerificationTTL).Unix(),
+	}
+	if err := s.users.SetSummonerVerification(v); err != nil {
+		render.Render(w, r, ErrDB(err))
+		return
+	}
+	render.Status(r, http.StatusCreated)
+	render.Render(w, r, NewSummonerVerificationResponse(v))
+}
+
+// VerifySummonerLink links the summoner of the pending verification to the
+// authenticated user once its third party code matches, it becomes the primary
+// account of its region if the user has none there
+func (s *Server) VerifySummonerLink(w http.ResponseWriter, r *http.Request) {
+	userID := protectedID(r)
+	v, err := s.users.GetSummonerVerification(userID)
+	if err != nil {
+		render.Render(w, r, ErrDB(err))
+		return
+	}
+	if time.Now().Unix() >= v.ExpiresAt {
+		render.Render(w, r, ErrGone(errVerificationExpired))
+		return
+	}
+	err = s.checkThirdPartyCode(r.Context(), riot.Platform(v.Region), v.SummonerID, v.Code)
+	if err != nil {
+		render.Render(w, r, ErrRiot(err))
+		return
+	}
+	account, err := s.users.LinkAccount(v)
+	if err != nil {
+		render.Render(w, r, ErrDB(err))
+		return
+	}
+	render.Render(w, r, NewLinkedAccountResponse(account))
+}
+
+// UnlinkSummoner removes every account linked to the authenticated user
+func (s *Server) UnlinkSummoner(w http.ResponseWriter, r *http.Request) {
+	if err := s.users.UnlinkAllAccounts(protectedID(r)); err != nil {
+		render.Render(w, r, ErrDB(err))
+		return
+	}
+	render.NoContent(w, r)
+}
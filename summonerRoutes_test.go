@@ -0,0 +1,94 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/anthonyrouseau/lss-api/riot"
+)
+
+func TestSummonerLink(t *testing.T) {
+	ts := newTestServer(t)
+	userID, user := ts.signup("player")
+	_, other := ts.signup("other")
+	account := &riot.Account{PUUID: "puuid-1", GameName: "Faker Fan", TagLine: "NA1"}
+	summoner := &riot.Summoner{ID: "summoner-1"}
+	ts.riot.AddSummoner(riot.NA1, account, summoner, "")
+	link := map[string]string{"riotId": "Faker Fan#NA1", "region": "na1"}
+
+	if status := ts.do("POST", "/user/me/summoner/verify", user.Token, nil, nil); status != http.StatusNotFound {
+		t.Errorf("verify without a pending link: status %d, want %d", status, http.StatusNotFound)
+	}
+	var pending SummonerVerification
+	if status := ts.do("POST", "/user/me/summoner", user.Token, link, &pending); status != http.StatusCreated {
+		t.Fatalf("start: status %d", status)
+	}
+	if pending.Code == "" || pending.PUUID != "puuid-1" || pending.SummonerID != "summoner-1" {
+		t.Errorf("pending link = %+v", pending)
+	}
+	if status := ts.do("POST", "/user/me/summoner/verify", user.Token, nil, nil); status != http.StatusBadRequest {
+		t.Errorf("verify before setting the code: status %d, want %d", status, http.StatusBadRequest)
+	}
+	ts.riot.AddSummoner(riot.NA1, account, summoner, pending.Code)
+	var linked LinkedAccount
+	if status := ts.do("POST", "/user/me/summoner/verify", user.Token, nil, &linked); status != http.StatusOK {
+		t.Fatalf("verify: status %d", status)
+	}
+	if linked.UserID != userID || linked.PUUID != "puuid-1" || !linked.Primary {
+		t.Errorf("linked account = %+v", linked)
+	}
+	if status := ts.do("POST", "/user/me/summoner/verify", user.Token, nil, nil); status != http.StatusNotFound {
+		t.Errorf("verify twice: status %d, want %d", status, http.StatusNotFound)
+	}
+
+	tests := []struct {
+		name   string
+		token  string
+		body   interface{}
+		status int
+	}{
+		{"summoner linked to another user", other.Token, link, http.StatusConflict},
+		{"unknown riot id", other.Token, map[string]string{"riotId": "Nobody#NA1"}, http.StatusBadRequest},
+		{"riot id not valid", other.Token, map[string]string{"riotId": "Nobody"}, http.StatusUnprocessableEntity},
+		{"unknown region", other.Token, map[string]string{"riotId": "Faker Fan#NA1", "region": "moon1"}, http.StatusUnprocessableEntity},
+		{"unauthenticated", "", link, http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if status := ts.do("POST", "/user/me/summoner", tt.token, tt.body, nil); status != tt.status {
+				t.Errorf("status = %d, want %d", status, tt.status)
+			}
+		})
+	}
+
+	if status := ts.do("DELETE", "/user/me/summoner", user.Token, nil, nil); status != http.StatusNoContent {
+		t.Fatalf("unlink: status %d", status)
+	}
+	if status := ts.do("DELETE", "/user/me/summoner", user.Token, nil, nil); status != http.StatusNotFound {
+		t.Errorf("unlink twice: status %d, want %d", status, http.StatusNotFound)
+	}
+	// once unlinked the summoner is free for another user
+	if status := ts.do("POST", "/user/me/summoner", other.Token, link, nil); status != http.StatusCreated {
+		t.Errorf("start after unlinking: status %d", status)
+	}
+}
+
+func TestVerifyExpiredSummonerLink(t *testing.T) {
+	ts := newTestServer(t)
+	userID, user := ts.signup("player")
+	ts.riot.AddSummoner(riot.NA1, &riot.Account{PUUID: "puuid-1", GameName: "Faker Fan", TagLine: "NA1"}, &riot.Summoner{ID: "summoner-1"}, "LSS-CODE")
+	past := time.Now().Add(-time.Hour)
+	v := &SummonerVerification{UserID: userID, RiotID: "Faker Fan#NA1", PUUID: "puuid-1", SummonerID: "summoner-1", Region: "na1",
+		Code: "LSS-CODE", CreatedAt: past.Unix(), ExpiresAt: past.Add(summonerVerificationTTL).Unix()}
+	if err := ts.store.SetSummonerVerification(v); err != nil {
+		t.Fatal(err)
+	}
+	if status := ts.do("POST", "/user/me/summoner/verify", user.Token, nil, nil); status != http.StatusGone {
+		t.Errorf("verify expired link: status %d, want %d", status, http.StatusGone)
+	}
+	accounts, err := ts.store.GetLinkedAccounts(userID)
+	if err != nil || len(accounts) != 0 {
+		t.Errorf("accounts after an expired link = %+v, %v", accounts, err)
+	}
+}
@@ -4,23 +4,53 @@ import (
 	"database/sql"
 	"errors"
 	"net/http"
+	"time"
 
 	"github.com/go-chi/render"
 )
 
 // Team is a representation of a Team entity in the database
+// Region is the riot platform the team plays on, na1 if not given
+// AcceptsRequests controls whether players can ask to join
+// DisbandedAt is only set on teams kept as tombstones after being disbanded
+// AverageRank is computed from the roster when a team is sent to the client
 type Team struct {
-	ID      int64  `json:"teamId,omitempty"`
-	Name    string `json:"name,omitempty"`
-	Captain int64  `json:"captain,omitempty"`
+	ID              int64     `json:"teamId,omitempty"`
+	Name            string    `json:"name,omitempty" validate:"required,min=3,max=32,charset=name,trimmed,notreserved,clean"`
+	Captain         int64     `json:"captain,omitempty"`
+	Region          string    `json:"region,omitempty"`
+	AcceptsRequests bool      `json:"acceptsRequests"`
+	DisbandedAt     int64     `json:"disbandedAt,omitempty"`
+	AverageRank     *TeamRank `json:"averageRank,omitempty"`
 }
 
+var (
+	errTeamNotFound       = newError(KindNotFound, CodeTeamNotFound, "team not found")
+	errCaptainCannotLeave = newError(KindForbidden, CodeTeamCaptainCannotLeave, "captain must transfer captaincy before leaving team")
+	errActionNotValid     = newError(KindValidation, CodeTeamActionInvalid, "action is not valid")
+)
+
 // TeamRequest is a representation of request to team routes
 type TeamRequest struct {
 	*Team
 	ProtectedID int64  `json:"userId"`
 	Action      string `json:"action,omitempty"`
-	Invitee     int64  `json:"invitee,omitempty"`
+}
+
+// TeamSettingsRequest is a request to change team settings
+// nil fields are left unchanged
+type TeamSettingsRequest struct {
+	AcceptsRequests *bool `json:"acceptsRequests"`
+}
+
+// Bind allows for preprocessing of requests
+func (sr *TeamSettingsRequest) Bind(r *http.Request) error {
+	if sr.AcceptsRequests == nil {
+		v := &ValidationError{}
+		v.Add("acceptsRequests", "is required")
+		return v
+	}
+	return nil
 }
 
 // TeamResponse is representation of response from team routes
@@ -33,8 +63,23 @@ func (tr *TeamRequest) Bind(r *http.Request) error {
 	if tr.Team == nil {
 		return errors.New("missing team fields")
 	}
-	//tr.ProtectedId = -1
-	return nil
+	v := &ValidationError{}
+	if r.Method == http.MethodPost {
+		rulesFromRequest(r).Validate(tr.Team, v)
+		platform, _ := bindRiotAccount(tr.Team.Region, "", v)
+		tr.Team.Region = string(platform)
+	} else {
+		// roster changes name the team by id and don't touch its fields
+		if tr.Team.ID <= 0 {
+			v.Add("teamId", "is required")
+		}
+		if tr.Action != "remove" {
+			v.Add("action", "must be remove")
+		}
+	}
+	tr.Team.AverageRank = nil
+	tr.ProtectedID = protectedID(r)
+	return v.Err()
 }
 
 // NewTeamResponse creates a TeamResponse
@@ -52,6 +97,25 @@ func (tr *TeamResponse) Render(w http.ResponseWriter, r *http.Request) error {
 	return nil
 }
 
+// TeamDetailResponse is a team along with its roster
+type TeamDetailResponse struct {
+	*Team
+	Roster []*RosterMember `json:"roster"`
+}
+
+// NewTeamDetailResponse creates a TeamDetailResponse
+func NewTeamDetailResponse(t *Team, roster []*RosterMember) *TeamDetailResponse {
+	if roster == nil {
+		roster = []*RosterMember{}
+	}
+	return &TeamDetailResponse{Team: t, Roster: roster}
+}
+
+// Render allows for preprocessing of TeamDetailResponse
+func (tr *TeamDetailResponse) Render(w http.ResponseWriter, r *http.Request) error {
+	return nil
+}
+
 // TeamListResponse is a list of TeamResponses
 type TeamListResponse []*TeamResponse
 
@@ -64,36 +128,38 @@ func NewTeamListResponse(teams []*Team) []render.Renderer {
 	return list
 }
 
-func dbNewTeam(team *Team) (int64, error) {
-	stmt, err := db.Prepare("INSERT INTO team(name,captain) VALUES(?,?)")
-	if err != nil {
-		return 0, err
-	}
-	res, err := stmt.Exec(team.Name, team.Captain)
-	if err != nil {
-		return 0, err
-	}
-	id, err := res.LastInsertId()
+// CreateTeam creates a team and adds its captain to the roster in one transaction
+func (m *SQLStore) CreateTeam(team *Team) error {
+	return m.withTx("create team", func(tx *sqlTx) error {
+		id, err := newTeam(tx, team)
+		if err != nil {
+			return err
+		}
+		err = addToRosterAs(tx, team.Captain, id, roleCaptain)
+		if err != nil {
+			return err
+		}
+		team.ID = id
+		return nil
+	})
+}
+
+func newTeam(p preparer, team *Team) (int64, error) {
+	id, err := p.insertID("INSERT INTO team(name,captain,region,acceptsRequests) VALUES(?,?,?,?)", team.Name, team.Captain, team.Region, team.AcceptsRequests)
 	if err != nil {
 		return 0, err
 	}
 	return id, nil
 }
 
-func dbAddToRoster(userID, teamID int64) error {
-	stmt, err := db.Prepare("INSERT INTO roster(teamID,userID) VALUES(?,?)")
-	if err != nil {
-		return err
-	}
-	_, err = stmt.Exec(teamID, userID)
-	if err != nil {
-		return err
-	}
-	return nil
+// addToRoster adds userID to teamID as a member, callers must first check the
+// invite, join link or join request that lets the player join
+func addToRoster(p preparer, userID, teamID int64) error {
+	return addToRosterAs(p, userID, teamID, roleMember)
 }
 
-func dbRemoveFromRoster(userID, teamID int64) error {
-	stmt, err := db.Prepare("DELETE FROM roster WHERE teamID=? AND userID=?")
+func (m *SQLStore) removeFromRoster(userID, teamID int64) error {
+	stmt, err := m.db.Prepare("DELETE FROM roster WHERE teamID=? AND userID=?")
 	if err != nil {
 		return err
 	}
@@ -104,16 +170,17 @@ func dbRemoveFromRoster(userID, teamID int64) error {
 	return nil
 }
 
-func dbSearchTeamName(searchValue, offset string) ([]*Team, error) {
+// SearchTeamName returns a page of teams with names starting with searchValue
+func (m *SQLStore) SearchTeamName(searchValue string, offset int) ([]*Team, error) {
 	var teams []*Team
-	rows, err := db.Query("SELECT id, name, captain FROM team WHERE name LIKE CONCAT(?, '%') LIMIT 10 OFFSET ?", searchValue, offset)
+	rows, err := m.db.Query("SELECT id, name, captain, region, acceptsRequests FROM team WHERE name {startswith} AND disbandedAt IS NULL LIMIT 10 OFFSET ?", searchValue, offset)
 	if err != nil {
 		return teams, err
 	}
 	defer rows.Close()
 	for rows.Next() {
 		var team Team
-		err := rows.Scan(&team.ID, &team.Name, &team.Captain)
+		err := rows.Scan(&team.ID, &team.Name, &team.Captain, &team.Region, &team.AcceptsRequests)
 		if err != nil {
 			return teams, err
 		}
@@ -126,16 +193,33 @@ func dbSearchTeamName(searchValue, offset string) ([]*Team, error) {
 	return teams, nil
 }
 
-func dbGetUserTeams(userID string) ([]*Team, error) {
+// GetTeam returns a team by ID, including disbanded teams, or errTeamNotFound
+func (m *SQLStore) GetTeam(teamID int64) (*Team, error) {
+	var team Team
+	var disbandedAt sql.NullInt64
+	err := m.db.QueryRow("SELECT id, name, captain, region, acceptsRequests, disbandedAt FROM team WHERE id=?", teamID).
+		Scan(&team.ID, &team.Name, &team.Captain, &team.Region, &team.AcceptsRequests, &disbandedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, errTeamNotFound
+		}
+		return nil, err
+	}
+	team.DisbandedAt = disbandedAt.Int64
+	return &team, nil
+}
+
+// GetUserTeams returns all teams of which userID is a member
+func (m *SQLStore) GetUserTeams(userID string) ([]*Team, error) {
 	var teams []*Team
-	rows, err := db.Query("SELECT id, name, captain FROM team INNER JOIN roster WHERE team.ID=roster.teamID AND roster.userID = ?", userID)
+	rows, err := m.db.Query("SELECT id, name, captain, region, acceptsRequests FROM team INNER JOIN roster ON team.ID=roster.teamID WHERE roster.userID = ?", userID)
 	if err != nil {
 		return teams, err
 	}
 	defer rows.Close()
 	for rows.Next() {
 		var team Team
-		err := rows.Scan(&team.ID, &team.Name, &team.Captain)
+		err := rows.Scan(&team.ID, &team.Name, &team.Captain, &team.Region, &team.AcceptsRequests)
 		if err != nil {
 			return teams, err
 		}
@@ -148,28 +232,27 @@ func dbGetUserTeams(userID string) ([]*Team, error) {
 	return teams, nil
 }
 
-func dbEditRoster(action string, userID, teamID int64) error {
+// EditRoster removes userID from the roster of teamID
+func (m *SQLStore) EditRoster(action string, userID, teamID int64) error {
 	switch action {
-	case "add":
-		return dbAddToRoster(userID, teamID)
 	case "remove":
-		var value bool
-		value, err := isCaptain(userID, teamID)
+		role, err := m.GetRosterRole(userID, teamID)
 		if err != nil {
 			return err
 		}
-		if value {
-			return errors.New("captain cannot leave team")
+		if role == roleCaptain {
+			return errCaptainCannotLeave
 		}
-		return dbRemoveFromRoster(userID, teamID)
+		return m.removeFromRoster(userID, teamID)
 	default:
-		return errors.New("action is not valid")
+		return errActionNotValid
 	}
 }
 
-func isCaptain(userID, teamID int64) (bool, error) {
+// IsCaptain reports whether userID is the captain of teamID
+func (m *SQLStore) IsCaptain(userID, teamID int64) (bool, error) {
 	var captain string
-	err := db.QueryRow("SELECT captain FROM team WHERE captain=? AND id=?", userID, teamID).Scan(&captain)
+	err := m.db.QueryRow("SELECT captain FROM team WHERE captain=? AND id=? AND disbandedAt IS NULL", userID, teamID).Scan(&captain)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return false, nil
@@ -178,3 +261,46 @@ func isCaptain(userID, teamID int64) (bool, error) {
 	}
 	return true, nil
 }
+
+// SetTeamAcceptsRequests sets whether a team accepts join requests
+func (m *SQLStore) SetTeamAcceptsRequests(teamID int64, accepts bool) error {
+	stmt, err := m.db.Prepare("UPDATE team SET acceptsRequests=? WHERE id=?")
+	if err != nil {
+		return err
+	}
+	_, err = stmt.Exec(accepts, teamID)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// DisbandTeam clears the roster, invites, join links and join requests of a team
+// the team row is kept as a tombstone so past results can still show its name
+func (m *SQLStore) DisbandTeam(teamID int64) error {
+	return m.withTx("disband team", func(tx *sqlTx) error {
+		res, err := tx.Exec("UPDATE team SET disbandedAt=?, acceptsRequests=? WHERE id=? AND disbandedAt IS NULL", time.Now().Unix(), false, teamID)
+		if err != nil {
+			return err
+		}
+		n, err := res.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if n == 0 {
+			return errTeamNotFound
+		}
+		for _, query := range []string{
+			"DELETE FROM roster WHERE teamID=?",
+			"DELETE FROM team_invite WHERE teamId=?",
+			"DELETE FROM team_join_link WHERE teamId=?",
+			"DELETE FROM team_join_request WHERE teamId=?",
+		} {
+			_, err = tx.Exec(query, teamID)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
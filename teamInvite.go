@@ -1,18 +1,54 @@
 package main
 
 import (
+	"database/sql"
 	"errors"
+	"log"
 	"net/http"
+	"time"
 
 	"github.com/go-chi/render"
 )
 
+const (
+	// inviteTTL is how long an invite can be accepted after it is sent
+	inviteTTL = 7 * 24 * time.Hour
+	// inviteRetention is how long expired invites are kept for captains to see
+	inviteRetention = 30 * 24 * time.Hour
+
+	inviteStatusPending = "pending"
+	inviteStatusExpired = "expired"
+)
+
 // TeamInvite represents an invite to a team
 // Name is used only for responses
 type TeamInvite struct {
-	Team    int64  `json:"teamId"`
-	Invitee int64  `json:"invitee"`
-	Name    string `json:"teamName,omitempty"`
+	ID        int64  `json:"inviteId,omitempty"`
+	Team      int64  `json:"teamId" validate:"required,min=1"`
+	Invitee   int64  `json:"invitee" validate:"required,min=1"`
+	Name      string `json:"teamName,omitempty"`
+	Status    string `json:"status,omitempty"`
+	CreatedAt int64  `json:"createdAt,omitempty"`
+	ExpiresAt int64  `json:"expiresAt,omitempty"`
+}
+
+var (
+	errInviteNotFound = newError(KindNotFound, CodeInviteNotFound, "invite not found")
+	errInviteExists   = newError(KindConflict, CodeInviteAlreadyExists, "invitee already has a pending invite to this team")
+	errInviteExpired  = newError(KindConflict, CodeInviteExpired, "invite has expired")
+)
+
+// Expired reports whether the invite can no longer be accepted
+// the sweeper may not have updated Status yet so ExpiresAt is checked too
+func (invite *TeamInvite) Expired() bool {
+	return invite.Status == inviteStatusExpired || time.Now().Unix() >= invite.ExpiresAt
+}
+
+// effectiveStatus sets Status to expired if the invite expired before the sweeper ran
+func (invite *TeamInvite) effectiveStatus() {
+	if invite.Expired() {
+		invite.Status = inviteStatusExpired
+	}
 }
 
 // TeamInviteRequest represents a request to teaminvite routes
@@ -31,8 +67,10 @@ func (i *TeamInviteRequest) Bind(r *http.Request) error {
 	if i.TeamInvite == nil {
 		return errors.New("missing invite fields")
 	}
-	//i.ProtectedID = -1
-	return nil
+	v := &ValidationError{}
+	rulesFromRequest(r).Validate(i.TeamInvite, v)
+	i.ProtectedID = protectedID(r)
+	return v.Err()
 }
 
 // NewTeamInviteResponse creates a response from and invite
@@ -53,7 +91,7 @@ func (ir *TeamInviteResponse) Render(w http.ResponseWriter, r *http.Request) err
 // TeamInviteListResponse  is a list of teaminvite responses
 type TeamInviteListResponse []*TeamInviteResponse
 
-//NewTeamInviteListResponse creates a list of teaminvite reponses for invites
+// NewTeamInviteListResponse creates a list of teaminvite reponses for invites
 func NewTeamInviteListResponse(invites []*TeamInvite) []render.Renderer {
 	list := []render.Renderer{}
 	for _, invite := range invites {
@@ -62,38 +100,128 @@ func NewTeamInviteListResponse(invites []*TeamInvite) []render.Renderer {
 	return list
 }
 
-func dbNewTeamInvite(invite *TeamInvite, userID int64) error {
-	value, err := isCaptain(userID, invite.Team)
+// NewTeamInvite creates a pending invite or returns errInviteExists if one is already live
+// an expired invite to the same invitee is replaced, the unique index on team and invitee
+// rejects invites created at the same time
+func (m *SQLStore) NewTeamInvite(invite *TeamInvite) error {
+	now := time.Now()
+	err := m.withTx("create invite", func(tx *sqlTx) error {
+		var existing int64
+		err := tx.QueryRow("SELECT id FROM team_invite WHERE teamId=? AND invitee=? AND status=? AND expiresAt > ?", invite.Team, invite.Invitee, inviteStatusPending, now.Unix()).Scan(&existing)
+		if err == nil {
+			return errInviteExists
+		}
+		if err != sql.ErrNoRows {
+			return err
+		}
+		_, err = tx.Exec("DELETE FROM team_invite WHERE teamId=? AND invitee=?", invite.Team, invite.Invitee)
+		if err != nil {
+			return err
+		}
+		invite.Status = inviteStatusPending
+		invite.CreatedAt = now.Unix()
+		invite.ExpiresAt = now.Add(inviteTTL).Unix()
+		id, err := tx.insertID("INSERT INTO team_invite(teamId,invitee,status,createdAt,expiresAt) VALUES(?,?,?,?,?)", invite.Team, invite.Invitee, invite.Status, invite.CreatedAt, invite.ExpiresAt)
+		if err != nil {
+			return err
+		}
+		invite.ID = id
+		return nil
+	})
+	if kindOf(err) == KindConflict {
+		return errInviteExists
+	}
+	return err
+}
+
+// GetTeamInvite returns an invite by ID or errInviteNotFound
+func (m *SQLStore) GetTeamInvite(inviteID int64) (*TeamInvite, error) {
+	var invite TeamInvite
+	err := m.db.QueryRow("SELECT team_invite.id,team.name,team_invite.teamId,team_invite.invitee,team_invite.status,team_invite.createdAt,team_invite.expiresAt FROM team INNER JOIN team_invite ON team.id=team_invite.teamId WHERE team_invite.id = ?", inviteID).
+		Scan(&invite.ID, &invite.Name, &invite.Team, &invite.Invitee, &invite.Status, &invite.CreatedAt, &invite.ExpiresAt)
 	if err != nil {
-		return err
+		if err == sql.ErrNoRows {
+			return nil, errInviteNotFound
+		}
+		return nil, err
 	}
-	if !value {
-		return errors.New("only captain can invite")
+	invite.effectiveStatus()
+	return &invite, nil
+}
+
+// AcceptTeamInvite adds the invitee to the roster and deletes the invite in one transaction
+func (m *SQLStore) AcceptTeamInvite(invite *TeamInvite) error {
+	return m.withTx("accept invite", func(tx *sqlTx) error {
+		err := addToRoster(tx, invite.Invitee, invite.Team)
+		if err != nil {
+			return err
+		}
+		return deleteTeamInvite(tx, invite.ID)
+	})
+}
+
+// DeleteTeamInvite deletes an invite or returns errInviteNotFound
+func (m *SQLStore) DeleteTeamInvite(inviteID int64) error {
+	return deleteTeamInvite(m.db, inviteID)
+}
+
+func deleteTeamInvite(p preparer, inviteID int64) error {
+	stmt, err := p.Prepare("DELETE FROM team_invite WHERE id=?")
+	if err != nil {
+		return err
 	}
-	stmt, err := db.Prepare("INSERT INTO team_invite(teamId,invitee) VALUES(?,?)")
+	res, err := stmt.Exec(inviteID)
 	if err != nil {
 		return err
 	}
-	_, err = stmt.Exec(invite.Team, invite.Invitee)
+	n, err := res.RowsAffected()
 	if err != nil {
 		return err
 	}
+	if n == 0 {
+		return errInviteNotFound
+	}
 	return nil
 }
 
-func dbGetUserTeamInvites(userID string) ([]*TeamInvite, error) {
+// GetUserTeamInvites returns the live invites sent to userID
+func (m *SQLStore) GetUserTeamInvites(userID string) ([]*TeamInvite, error) {
+	var invites []*TeamInvite
+	rows, err := m.db.Query("SELECT team_invite.id,team.name,team_invite.teamId,team_invite.invitee,team_invite.status,team_invite.createdAt,team_invite.expiresAt FROM team INNER JOIN team_invite ON team.id=team_invite.teamId WHERE team_invite.invitee = ? AND team_invite.status = ? AND team_invite.expiresAt > ?", userID, inviteStatusPending, time.Now().Unix())
+	if err != nil {
+		return invites, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var invite TeamInvite
+		err := rows.Scan(&invite.ID, &invite.Name, &invite.Team, &invite.Invitee, &invite.Status, &invite.CreatedAt, &invite.ExpiresAt)
+		if err != nil {
+			return invites, err
+		}
+		invites = append(invites, &invite)
+	}
+	err = rows.Err()
+	if err != nil {
+		return invites, err
+	}
+	return invites, nil
+}
+
+// GetTeamInvites returns every invite sent by a team, newest first
+func (m *SQLStore) GetTeamInvites(teamID int64) ([]*TeamInvite, error) {
 	var invites []*TeamInvite
-	rows, err := db.Query("SELECT team.name,team_invite.teamId,team_invite.invitee FROM team INNER JOIN team_invite WHERE team.id=team_invite.teamId AND team_invite.invitee = ?", userID)
+	rows, err := m.db.Query("SELECT team_invite.id,team.name,team_invite.teamId,team_invite.invitee,team_invite.status,team_invite.createdAt,team_invite.expiresAt FROM team INNER JOIN team_invite ON team.id=team_invite.teamId WHERE team_invite.teamId = ? ORDER BY team_invite.createdAt DESC", teamID)
 	if err != nil {
 		return invites, err
 	}
 	defer rows.Close()
 	for rows.Next() {
 		var invite TeamInvite
-		err := rows.Scan(&invite.Name, &invite.Team, &invite.Invitee)
+		err := rows.Scan(&invite.ID, &invite.Name, &invite.Team, &invite.Invitee, &invite.Status, &invite.CreatedAt, &invite.ExpiresAt)
 		if err != nil {
 			return invites, err
 		}
+		invite.effectiveStatus()
 		invites = append(invites, &invite)
 	}
 	err = rows.Err()
@@ -102,3 +230,30 @@ func dbGetUserTeamInvites(userID string) ([]*TeamInvite, error) {
 	}
 	return invites, nil
 }
+
+// ExpireTeamInvites marks pending invites past their expiry as expired
+// and deletes expired invites older than inviteRetention
+func (m *SQLStore) ExpireTeamInvites(now time.Time) error {
+	_, err := m.db.Exec("UPDATE team_invite SET status=? WHERE status=? AND expiresAt <= ?", inviteStatusExpired, inviteStatusPending, now.Unix())
+	if err != nil {
+		return err
+	}
+	_, err = m.db.Exec("DELETE FROM team_invite WHERE status=? AND expiresAt <= ?", inviteStatusExpired, now.Add(-inviteRetention).Unix())
+	return err
+}
+
+// sweepTeamInvites expires invites every interval until stop is closed
+func (s *Server) sweepTeamInvites(interval time.Duration, stop <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case now := <-ticker.C:
+			if err := s.invites.ExpireTeamInvites(now); err != nil {
+				log.Printf("invite sweeper: %v", err)
+			}
+		case <-stop:
+			return
+		}
+	}
+}
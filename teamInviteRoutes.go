@@ -3,33 +3,125 @@ package main
 import (
 	"errors"
 	"net/http"
+	"strconv"
 
 	"github.com/go-chi/chi"
 	"github.com/go-chi/render"
-	"github.com/go-sql-driver/mysql"
 )
 
 // TeamInviteRoutes returns a router with team invite routes to be mounted in routes.go
-func TeamInviteRoutes() *chi.Mux {
+func (s *Server) TeamInviteRoutes() *chi.Mux {
 	r := chi.NewRouter()
-	r.Post("/", CreateTeamInvite)
-	r.Get("by-user/{userID}", GetUserTeamInvites)
+	r.Group(func(r chi.Router) {
+		r.Use(RequireAuth)
+		r.Get("/by-user/{userID}", s.GetUserTeamInvites)
+		r.Post("/", s.CreateTeamInvite)
+		r.Post("/{inviteID}/accept", s.AcceptTeamInvite)
+		r.Post("/{inviteID}/decline", s.DeclineTeamInvite)
+		r.Delete("/{inviteID}", s.RevokeTeamInvite)
+	})
 	return r
 }
 
+// loadTeamInvite gets the invite identified by the inviteID url param,
+// rendering an error and returning nil if it can't be found
+func (s *Server) loadTeamInvite(w http.ResponseWriter, r *http.Request) *TeamInvite {
+	inviteID, err := strconv.ParseInt(chi.URLParam(r, "inviteID"), 10, 64)
+	if err != nil {
+		render.Render(w, r, ErrBadRequest(errors.New("invite id not valid")))
+		return nil
+	}
+	invite, err := s.invites.GetTeamInvite(inviteID)
+	if err != nil {
+		if err == errInviteNotFound {
+			render.Render(w, r, ErrNotFound(err))
+			return nil
+		}
+		render.Render(w, r, ErrDB(err))
+		return nil
+	}
+	return invite
+}
+
+// AcceptTeamInvite adds the invitee to the team roster and removes the invite
+func (s *Server) AcceptTeamInvite(w http.ResponseWriter, r *http.Request) {
+	invite := s.loadTeamInvite(w, r)
+	if invite == nil {
+		return
+	}
+	if invite.Invitee != protectedID(r) {
+		render.Render(w, r, ErrForbidden(errors.New("not intended user")))
+		return
+	}
+	if invite.Expired() {
+		render.Render(w, r, ErrGone(errInviteExpired))
+		return
+	}
+	err := s.invites.AcceptTeamInvite(invite)
+	if err != nil {
+		if errors.Is(err, errInviteNotFound) {
+			render.Render(w, r, ErrNotFound(err))
+			return
+		}
+		render.Render(w, r, ErrDB(err))
+		return
+	}
+	render.Render(w, r, NewTeamInviteResponse(invite))
+}
+
+// DeclineTeamInvite removes an invite at the request of the invitee
+func (s *Server) DeclineTeamInvite(w http.ResponseWriter, r *http.Request) {
+	invite := s.loadTeamInvite(w, r)
+	if invite == nil {
+		return
+	}
+	if invite.Invitee != protectedID(r) {
+		render.Render(w, r, ErrForbidden(errors.New("not intended user")))
+		return
+	}
+	s.deleteInvite(w, r, invite)
+}
+
+// RevokeTeamInvite removes an invite at the request of the team captain or a co-captain
+func (s *Server) RevokeTeamInvite(w http.ResponseWriter, r *http.Request) {
+	invite := s.loadTeamInvite(w, r)
+	if invite == nil {
+		return
+	}
+	if !s.requireRosterManager(w, r, invite.Team) {
+		return
+	}
+	s.deleteInvite(w, r, invite)
+}
+
+func (s *Server) deleteInvite(w http.ResponseWriter, r *http.Request, invite *TeamInvite) {
+	err := s.invites.DeleteTeamInvite(invite.ID)
+	if err != nil {
+		if err == errInviteNotFound {
+			render.Render(w, r, ErrNotFound(err))
+			return
+		}
+		render.Render(w, r, ErrDB(err))
+		return
+	}
+	render.NoContent(w, r)
+}
+
 // CreateTeamInvite creates a team invite in the database
-func CreateTeamInvite(w http.ResponseWriter, r *http.Request) {
+func (s *Server) CreateTeamInvite(w http.ResponseWriter, r *http.Request) {
 	data := &TeamInviteRequest{}
 	if err := render.Bind(r, data); err != nil {
 		render.Render(w, r, ErrBadRequest(err))
 		return
 	}
 	invite := data.TeamInvite
-	err := dbNewTeamInvite(invite, data.ProtectedID)
+	if !s.requireRosterManager(w, r, invite.Team) {
+		return
+	}
+	err := s.invites.NewTeamInvite(invite)
 	if err != nil {
-		_, ok := err.(*mysql.MySQLError)
-		if !ok {
-			render.Render(w, r, ErrForbidden(err))
+		if errors.Is(err, errInviteExists) {
+			render.Render(w, r, ErrConflict(err))
 			return
 		}
 		render.Render(w, r, ErrDB(err))
@@ -38,16 +130,33 @@ func CreateTeamInvite(w http.ResponseWriter, r *http.Request) {
 	render.Render(w, r, NewTeamInviteResponse(invite))
 }
 
-// GetUserTeamInvites renders all team invites for a given userID
-func GetUserTeamInvites(w http.ResponseWriter, r *http.Request) {
-	var inviteList []*TeamInvite
-	var err error
-	if userID := chi.URLParam(r, "userID"); userID != "" {
-		inviteList, err = dbGetUserTeamInvites(userID)
-	} else {
-		render.Render(w, r, ErrBadRequest(errors.New("user id not valid")))
+// GetUserTeamInvites renders all team invites for a given userID, which must be the requesting user
+func (s *Server) GetUserTeamInvites(w http.ResponseWriter, r *http.Request) {
+	if !requireSelf(w, r) {
+		return
+	}
+	inviteList, err := s.invites.GetUserTeamInvites(chi.URLParam(r, "userID"))
+	if err != nil {
+		render.Render(w, r, ErrDB(err))
+		return
+	}
+	if err := render.RenderList(w, r, NewTeamInviteListResponse(inviteList)); err != nil {
+		render.Render(w, r, ErrRender(err))
+		return
+	}
+}
+
+// GetTeamInvites renders all invites sent by a team along with their status
+func (s *Server) GetTeamInvites(w http.ResponseWriter, r *http.Request) {
+	teamID, err := teamIDParam(r)
+	if err != nil {
+		render.Render(w, r, ErrBadRequest(err))
+		return
+	}
+	if !s.requireRosterManager(w, r, teamID) {
 		return
 	}
+	inviteList, err := s.invites.GetTeamInvites(teamID)
 	if err != nil {
 		render.Render(w, r, ErrDB(err))
 		return
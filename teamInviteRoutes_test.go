@@ -0,0 +1,54 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+)
+
+func TestCreateTeamInvite(t *testing.T) {
+	ts := newTestServer(t)
+	_, captain := ts.signup("captain")
+	playerID, player := ts.signup("player")
+	otherID, _ := ts.signup("other")
+	team := ts.createTeam(captain.Token, "Alpha Squad")
+
+	var invite TeamInvite
+	body := map[string]int64{"teamId": team.ID, "invitee": playerID}
+	if status := ts.do("POST", "/team/invite", captain.Token, body, &invite); status != http.StatusOK {
+		t.Fatalf("invite: status %d", status)
+	}
+	if invite.ID == 0 || invite.Status != inviteStatusPending || invite.ExpiresAt <= invite.CreatedAt {
+		t.Errorf("invite = %+v", invite)
+	}
+
+	tests := []struct {
+		name   string
+		token  string
+		body   interface{}
+		status int
+	}{
+		{"unauthenticated", "", map[string]int64{"teamId": team.ID, "invitee": otherID}, http.StatusUnauthorized},
+		{"not a roster manager", player.Token, map[string]int64{"teamId": team.ID, "invitee": otherID}, http.StatusForbidden},
+		{"missing invitee", captain.Token, map[string]int64{"teamId": team.ID}, http.StatusUnprocessableEntity},
+		{"pending invite", captain.Token, body, http.StatusConflict},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if status := ts.do("POST", "/team/invite", tt.token, tt.body, nil); status != tt.status {
+				t.Errorf("status = %d, want %d", status, tt.status)
+			}
+		})
+	}
+
+	var invites []*TeamInvite
+	if status := ts.do("GET", fmt.Sprintf("/team/invite/by-user/%d", playerID), player.Token, nil, &invites); status != http.StatusOK {
+		t.Fatalf("list invites: status %d", status)
+	}
+	if len(invites) != 1 || invites[0].ID != invite.ID {
+		t.Errorf("invites of player = %+v", invites)
+	}
+	if status := ts.do("GET", fmt.Sprintf("/team/invite/by-user/%d", playerID), captain.Token, nil, nil); status != http.StatusForbidden {
+		t.Errorf("listing another user's invites: status %d, want %d", status, http.StatusForbidden)
+	}
+}
@@ -0,0 +1,160 @@
+package main
+
+import (
+	"database/sql"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/render"
+)
+
+// TeamJoinLink represents a shareable code that adds whoever redeems it to a team
+// MaxUses and ExpiresAt are 0 when the link is unlimited
+type TeamJoinLink struct {
+	Code      string `json:"code"`
+	Team      int64  `json:"teamId"`
+	MaxUses   int    `json:"maxUses"`
+	Uses      int    `json:"uses"`
+	CreatedAt int64  `json:"createdAt"`
+	ExpiresAt int64  `json:"expiresAt"`
+}
+
+var (
+	errJoinLinkNotFound = newError(KindNotFound, CodeJoinLinkNotFound, "join link not found")
+	errJoinLinkInvalid  = newError(KindConflict, CodeJoinLinkInvalid, "join link is expired, revoked or used up")
+)
+
+// TeamJoinLinkRequest represents a request to create a join link
+// ExpiresIn is in seconds
+type TeamJoinLinkRequest struct {
+	MaxUses     int   `json:"maxUses"`
+	ExpiresIn   int64 `json:"expiresIn"`
+	ProtectedID int64 `json:"userId"`
+}
+
+// TeamJoinLinkResponse represents a join link sent to the client
+type TeamJoinLinkResponse struct {
+	*TeamJoinLink
+}
+
+// Bind allows for preprocessing of requests
+func (lr *TeamJoinLinkRequest) Bind(r *http.Request) error {
+	v := &ValidationError{}
+	if lr.MaxUses < 0 {
+		v.Add("maxUses", "cannot be negative")
+	}
+	if lr.ExpiresIn < 0 {
+		v.Add("expiresIn", "cannot be negative")
+	}
+	lr.ProtectedID = protectedID(r)
+	return v.Err()
+}
+
+// NewTeamJoinLinkResponse creates a response from a join link
+func NewTeamJoinLinkResponse(link *TeamJoinLink) *TeamJoinLinkResponse {
+	return &TeamJoinLinkResponse{TeamJoinLink: link}
+}
+
+// Render allows for preprocessing of responses
+func (lr *TeamJoinLinkResponse) Render(w http.ResponseWriter, r *http.Request) error {
+	return nil
+}
+
+// NewTeamJoinLinkListResponse creates a list of join link responses
+func NewTeamJoinLinkListResponse(links []*TeamJoinLink) []render.Renderer {
+	list := []render.Renderer{}
+	for _, link := range links {
+		list = append(list, NewTeamJoinLinkResponse(link))
+	}
+	return list
+}
+
+// NewTeamJoinLink creates a join link with a random code
+func (m *SQLStore) NewTeamJoinLink(link *TeamJoinLink, createdBy int64) error {
+	code, err := randomToken(16)
+	if err != nil {
+		return err
+	}
+	link.Code = code
+	link.CreatedAt = time.Now().Unix()
+	stmt, err := m.db.Prepare("INSERT INTO team_join_link(code,teamId,createdBy,maxUses,uses,createdAt,expiresAt) VALUES(?,?,?,?,?,?,?)")
+	if err != nil {
+		return err
+	}
+	_, err = stmt.Exec(link.Code, link.Team, createdBy, link.MaxUses, link.Uses, link.CreatedAt, link.ExpiresAt)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// GetTeamJoinLinks returns the join links of a team that have not been revoked
+func (m *SQLStore) GetTeamJoinLinks(teamID int64) ([]*TeamJoinLink, error) {
+	var links []*TeamJoinLink
+	rows, err := m.db.Query("SELECT code, teamId, maxUses, uses, createdAt, expiresAt FROM team_join_link WHERE teamId=? AND revokedAt IS NULL ORDER BY createdAt DESC", teamID)
+	if err != nil {
+		return links, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var link TeamJoinLink
+		err := rows.Scan(&link.Code, &link.Team, &link.MaxUses, &link.Uses, &link.CreatedAt, &link.ExpiresAt)
+		if err != nil {
+			return links, err
+		}
+		links = append(links, &link)
+	}
+	err = rows.Err()
+	if err != nil {
+		return links, err
+	}
+	return links, nil
+}
+
+// RevokeTeamJoinLink stops a join link from being redeemed
+func (m *SQLStore) RevokeTeamJoinLink(code string, teamID int64) error {
+	res, err := m.db.Exec("UPDATE team_join_link SET revokedAt=? WHERE code=? AND teamId=? AND revokedAt IS NULL", time.Now().Unix(), code, teamID)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return errJoinLinkNotFound
+	}
+	return nil
+}
+
+// RedeemTeamJoinLink counts a use of the link and adds userID to the roster in one transaction
+// links of a disbanded team can't be redeemed
+func (m *SQLStore) RedeemTeamJoinLink(code string, userID int64) (int64, error) {
+	var teamID int64
+	err := m.withTx("redeem join link", func(tx *sqlTx) error {
+		err := tx.QueryRow("SELECT teamId FROM team_join_link WHERE code=?", code).Scan(&teamID)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				return errJoinLinkNotFound
+			}
+			return err
+		}
+		res, err := tx.Exec(`UPDATE team_join_link SET uses=uses+1 WHERE code=? AND revokedAt IS NULL AND (maxUses=0 OR uses<maxUses) AND (expiresAt=0 OR expiresAt>?)
+			AND EXISTS (SELECT 1 FROM team WHERE team.id=team_join_link.teamId AND team.disbandedAt IS NULL)`, code, time.Now().Unix())
+		if err != nil {
+			return err
+		}
+		n, err := res.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if n == 0 {
+			return errJoinLinkInvalid
+		}
+		return addToRoster(tx, userID, teamID)
+	})
+	if err != nil {
+		return 0, err
+	}
+	return teamID, nil
+}
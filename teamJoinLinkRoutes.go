@@ -0,0 +1,103 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi"
+	"github.com/go-chi/render"
+)
+
+// CreateTeamJoinLink creates a join link for a team
+func (s *Server) CreateTeamJoinLink(w http.ResponseWriter, r *http.Request) {
+	teamID, err := teamIDParam(r)
+	if err != nil {
+		render.Render(w, r, ErrBadRequest(err))
+		return
+	}
+	data := &TeamJoinLinkRequest{}
+	if err := render.Bind(r, data); err != nil {
+		render.Render(w, r, ErrBadRequest(err))
+		return
+	}
+	if !s.requireRosterManager(w, r, teamID) {
+		return
+	}
+	link := &TeamJoinLink{Team: teamID, MaxUses: data.MaxUses}
+	if data.ExpiresIn > 0 {
+		link.ExpiresAt = time.Now().Add(time.Duration(data.ExpiresIn) * time.Second).Unix()
+	}
+	err = s.invites.NewTeamJoinLink(link, data.ProtectedID)
+	if err != nil {
+		render.Render(w, r, ErrDB(err))
+		return
+	}
+	render.Status(r, http.StatusCreated)
+	render.Render(w, r, NewTeamJoinLinkResponse(link))
+}
+
+// GetTeamJoinLinks renders the join links of a team that have not been revoked
+func (s *Server) GetTeamJoinLinks(w http.ResponseWriter, r *http.Request) {
+	teamID, err := teamIDParam(r)
+	if err != nil {
+		render.Render(w, r, ErrBadRequest(err))
+		return
+	}
+	if !s.requireRosterManager(w, r, teamID) {
+		return
+	}
+	linkList, err := s.invites.GetTeamJoinLinks(teamID)
+	if err != nil {
+		render.Render(w, r, ErrDB(err))
+		return
+	}
+	if err := render.RenderList(w, r, NewTeamJoinLinkListResponse(linkList)); err != nil {
+		render.Render(w, r, ErrRender(err))
+		return
+	}
+}
+
+// RevokeTeamJoinLink stops a join link from being redeemed
+func (s *Server) RevokeTeamJoinLink(w http.ResponseWriter, r *http.Request) {
+	teamID, err := teamIDParam(r)
+	if err != nil {
+		render.Render(w, r, ErrBadRequest(err))
+		return
+	}
+	if !s.requireRosterManager(w, r, teamID) {
+		return
+	}
+	err = s.invites.RevokeTeamJoinLink(chi.URLParam(r, "code"), teamID)
+	if err != nil {
+		if err == errJoinLinkNotFound {
+			render.Render(w, r, ErrNotFound(err))
+			return
+		}
+		render.Render(w, r, ErrDB(err))
+		return
+	}
+	render.NoContent(w, r)
+}
+
+// RedeemTeamJoinLink adds the requesting user to the team of a join link and renders the team
+func (s *Server) RedeemTeamJoinLink(w http.ResponseWriter, r *http.Request) {
+	teamID, err := s.invites.RedeemTeamJoinLink(chi.URLParam(r, "code"), protectedID(r))
+	if err != nil {
+		switch {
+		case errors.Is(err, errJoinLinkNotFound):
+			render.Render(w, r, ErrNotFound(err))
+		case errors.Is(err, errJoinLinkInvalid):
+			render.Render(w, r, ErrGone(err))
+		default:
+			render.Render(w, r, ErrDB(err))
+		}
+		return
+	}
+	team, err := s.teams.GetTeam(teamID)
+	if err != nil {
+		render.Render(w, r, ErrDB(err))
+		return
+	}
+	render.Render(w, r, NewTeamResponse(team))
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+)
+
+func TestTeamJoinLinkRoutes(t *testing.T) {
+	ts := newTestServer(t)
+	captainID, captain := ts.signup("captain")
+	_, player := ts.signup("player")
+	_, other := ts.signup("other")
+	team := ts.createTeam(captain.Token, "Alpha Squad")
+	links := fmt.Sprintf("/team/%d/join-links", team.ID)
+
+	if status := ts.do("POST", links, player.Token, map[string]int{"maxUses": 1}, nil); status != http.StatusForbidden {
+		t.Errorf("create link as a player: status %d, want %d", status, http.StatusForbidden)
+	}
+	if status := ts.do("POST", links, captain.Token, map[string]int{"maxUses": -1}, nil); status != http.StatusUnprocessableEntity {
+		t.Errorf("create link with negative uses: status %d, want %d", status, http.StatusUnprocessableEntity)
+	}
+	var link TeamJoinLink
+	if status := ts.do("POST", links, captain.Token, map[string]int{"maxUses": 1}, &link); status != http.StatusCreated {
+		t.Fatalf("create link: status %d", status)
+	}
+
+	var joined Team
+	if status := ts.do("POST", "/team/join/"+link.Code, player.Token, nil, &joined); status != http.StatusOK {
+		t.Fatalf("redeem: status %d", status)
+	}
+	if joined.ID != team.ID || joined.Name != team.Name || joined.Captain != captainID || joined.Region != team.Region {
+		t.Errorf("redeem rendered %+v, want %+v", joined, team)
+	}
+	if status := ts.do("POST", "/team/join/"+link.Code, other.Token, nil, nil); status != http.StatusGone {
+		t.Errorf("redeem used up link: status %d, want %d", status, http.StatusGone)
+	}
+	if status := ts.do("POST", "/team/join/missing", other.Token, nil, nil); status != http.StatusNotFound {
+		t.Errorf("redeem unknown link: status %d, want %d", status, http.StatusNotFound)
+	}
+
+	var list []*TeamJoinLink
+	if status := ts.do("GET", links, captain.Token, nil, &list); status != http.StatusOK {
+		t.Fatalf("list links: status %d", status)
+	}
+	if len(list) != 1 || list[0].Uses != 1 {
+		t.Errorf("links = %+v, want one link used once", list)
+	}
+	if status := ts.do("DELETE", links+"/"+link.Code, captain.Token, nil, nil); status != http.StatusNoContent {
+		t.Errorf("revoke: status %d, want %d", status, http.StatusNoContent)
+	}
+	if status := ts.do("DELETE", links+"/"+link.Code, captain.Token, nil, nil); status != http.StatusNotFound {
+		t.Errorf("revoke twice: status %d, want %d", status, http.StatusNotFound)
+	}
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestRedeemTeamJoinLink(t *testing.T) {
+	forEachStore(t, func(t *testing.T, store Store) {
+		captain := newTestUser(t, store, "captain")
+		first := newTestUser(t, store, "first")
+		second := newTestUser(t, store, "second")
+		team := newTestTeam(t, store, captain)
+
+		once := &TeamJoinLink{Team: team.ID, MaxUses: 1}
+		if err := store.NewTeamJoinLink(once, captain); err != nil {
+			t.Fatal(err)
+		}
+		teamID, err := store.RedeemTeamJoinLink(once.Code, first)
+		if err != nil || teamID != team.ID {
+			t.Fatalf("redeem = %d, %v, want %d", teamID, err, team.ID)
+		}
+		if _, err := store.RedeemTeamJoinLink(once.Code, second); !errors.Is(err, errJoinLinkInvalid) {
+			t.Errorf("redeem used up link: %v, want %v", err, errJoinLinkInvalid)
+		}
+
+		unlimited := &TeamJoinLink{Team: team.ID}
+		if err := store.NewTeamJoinLink(unlimited, captain); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := store.RedeemTeamJoinLink(unlimited.Code, first); kindOf(err) != KindConflict {
+			t.Errorf("redeem while on the roster: %v, want a conflict", err)
+		}
+		links, err := store.GetTeamJoinLinks(team.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, link := range links {
+			if link.Code == unlimited.Code && link.Uses != 0 {
+				t.Errorf("failed redeem counted a use: %+v", link)
+			}
+		}
+
+		if err := store.RevokeTeamJoinLink(unlimited.Code, team.ID); err != nil {
+			t.Fatal(err)
+		}
+		if err := store.RevokeTeamJoinLink(unlimited.Code, team.ID); !errors.Is(err, errJoinLinkNotFound) {
+			t.Errorf("revoke twice: %v, want %v", err, errJoinLinkNotFound)
+		}
+		if _, err := store.RedeemTeamJoinLink(unlimited.Code, second); !errors.Is(err, errJoinLinkInvalid) {
+			t.Errorf("redeem revoked link: %v, want %v", err, errJoinLinkInvalid)
+		}
+
+		expired := &TeamJoinLink{Team: team.ID, ExpiresAt: time.Now().Add(-time.Minute).Unix()}
+		if err := store.NewTeamJoinLink(expired, captain); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := store.RedeemTeamJoinLink(expired.Code, second); !errors.Is(err, errJoinLinkInvalid) {
+			t.Errorf("redeem expired link: %v, want %v", err, errJoinLinkInvalid)
+		}
+		if _, err := store.RedeemTeamJoinLink("missing", second); !errors.Is(err, errJoinLinkNotFound) {
+			t.Errorf("redeem unknown link: %v, want %v", err, errJoinLinkNotFound)
+		}
+	})
+}
+
+func TestRedeemTeamJoinLinkOfDisbandedTeam(t *testing.T) {
+	// disbanding deletes the links of a team, this covers a redeem that read the link first
+	store := newTestSQLStore(t)
+	captain := newTestUser(t, store, "captain")
+	player := newTestUser(t, store, "player")
+	team := newTestTeam(t, store, captain)
+	link := &TeamJoinLink{Team: team.ID}
+	if err := store.NewTeamJoinLink(link, captain); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.db.Exec("UPDATE team SET disbandedAt=? WHERE id=?", time.Now().Unix(), team.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.RedeemTeamJoinLink(link.Code, player); !errors.Is(err, errJoinLinkInvalid) {
+		t.Errorf("redeem link of a disbanded team: %v, want %v", err, errJoinLinkInvalid)
+	}
+	if _, err := store.GetRosterRole(player, team.ID); !errors.Is(err, errNotOnRoster) {
+		t.Errorf("player was added to the disbanded team: %v", err)
+	}
+}
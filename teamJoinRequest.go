@@ -0,0 +1,199 @@
+package main
+
+import (
+	"database/sql"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/render"
+)
+
+// maxOpenJoinRequests is how many pending join requests a player can have at once
+const maxOpenJoinRequests = 5
+
+const (
+	joinRequestPending  = "pending"
+	joinRequestApproved = "approved"
+	joinRequestRejected = "rejected"
+)
+
+// TeamJoinRequest represents a player asking to join a team
+type TeamJoinRequest struct {
+	ID        int64  `json:"requestId"`
+	Team      int64  `json:"teamId"`
+	User      int64  `json:"userId"`
+	Message   string `json:"message,omitempty"`
+	Status    string `json:"status"`
+	CreatedAt int64  `json:"createdAt"`
+	DecidedAt int64  `json:"decidedAt,omitempty"`
+}
+
+var (
+	errJoinRequestNotFound = newError(KindNotFound, CodeJoinRequestNotFound, "join request not found")
+	errJoinRequestExists   = newError(KindConflict, CodeJoinRequestAlreadyExists, "a join request to this team is already pending")
+	errJoinRequestLimit    = newError(KindConflict, CodeJoinRequestLimit, "too many pending join requests")
+	errJoinRequestsClosed  = newError(KindForbidden, CodeJoinRequestsClosed, "team is not accepting join requests")
+	errAlreadyOnRoster     = newError(KindConflict, CodeRosterMemberExists, "player is already on the roster")
+)
+
+// TeamJoinRequestRequest represents a request to create a join request
+type TeamJoinRequestRequest struct {
+	Message     string `json:"message"`
+	ProtectedID int64  `json:"userId"`
+}
+
+// TeamJoinRequestResponse represents a join request sent to the client
+type TeamJoinRequestResponse struct {
+	*TeamJoinRequest
+}
+
+// Bind allows for preprocessing of requests
+func (jr *TeamJoinRequestRequest) Bind(r *http.Request) error {
+	if len(jr.Message) > 500 {
+		v := &ValidationError{}
+		v.Add("message", "must be at most 500 characters")
+		return v
+	}
+	jr.ProtectedID = protectedID(r)
+	return nil
+}
+
+// NewTeamJoinRequestResponse creates a response from a join request
+func NewTeamJoinRequestResponse(req *TeamJoinRequest) *TeamJoinRequestResponse {
+	return &TeamJoinRequestResponse{TeamJoinRequest: req}
+}
+
+// Render allows for preprocessing of responses
+func (jr *TeamJoinRequestResponse) Render(w http.ResponseWriter, r *http.Request) error {
+	return nil
+}
+
+// NewTeamJoinRequestListResponse creates a list of join request responses
+func NewTeamJoinRequestListResponse(reqs []*TeamJoinRequest) []render.Renderer {
+	list := []render.Renderer{}
+	for _, req := range reqs {
+		list = append(list, NewTeamJoinRequestResponse(req))
+	}
+	return list
+}
+
+// NewTeamJoinRequest creates a pending join request if the team accepts them, the player is
+// not on its roster and is under the limit
+// the player's account row is locked so their requests are counted one at a time, and a
+// unique index keeps a single pending request per team and player
+func (m *SQLStore) NewTeamJoinRequest(req *TeamJoinRequest) error {
+	var accepts bool
+	err := m.db.QueryRow("SELECT acceptsRequests FROM team WHERE id=? AND disbandedAt IS NULL", req.Team).Scan(&accepts)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return errTeamNotFound
+		}
+		return err
+	}
+	if !accepts {
+		return errJoinRequestsClosed
+	}
+	err = m.withTx("create join request", func(tx *sqlTx) error {
+		var userID int64
+		err := tx.QueryRow("SELECT id FROM account WHERE id=?{forupdate}", req.User).Scan(&userID)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				return errUserNotFound
+			}
+			return err
+		}
+		var onRoster int
+		err = tx.QueryRow("SELECT COUNT(*) FROM roster WHERE teamID=? AND userID=?", req.Team, req.User).Scan(&onRoster)
+		if err != nil {
+			return err
+		}
+		if onRoster > 0 {
+			return errAlreadyOnRoster
+		}
+		var open, sameTeam int
+		err = tx.QueryRow("SELECT COUNT(*), COALESCE(SUM(CASE WHEN teamId=? THEN 1 ELSE 0 END), 0) FROM team_join_request WHERE userId=? AND status=?", req.Team, req.User, joinRequestPending).Scan(&open, &sameTeam)
+		if err != nil {
+			return err
+		}
+		if sameTeam > 0 {
+			return errJoinRequestExists
+		}
+		if open >= maxOpenJoinRequests {
+			return errJoinRequestLimit
+		}
+		req.Status = joinRequestPending
+		req.CreatedAt = time.Now().Unix()
+		id, err := tx.insertID("INSERT INTO team_join_request(teamId,userId,message,status,createdAt) VALUES(?,?,?,?,?)", req.Team, req.User, req.Message, req.Status, req.CreatedAt)
+		if err != nil {
+			return err
+		}
+		req.ID = id
+		return nil
+	})
+	if dbErr, ok := asDBError(err); ok && dbErr.Kind == KindConflict {
+		return errJoinRequestExists
+	}
+	return err
+}
+
+// GetTeamJoinRequests returns the pending join requests of a team, oldest first
+func (m *SQLStore) GetTeamJoinRequests(teamID int64) ([]*TeamJoinRequest, error) {
+	var reqs []*TeamJoinRequest
+	rows, err := m.db.Query("SELECT id, teamId, userId, message, status, createdAt FROM team_join_request WHERE teamId=? AND status=? ORDER BY createdAt", teamID, joinRequestPending)
+	if err != nil {
+		return reqs, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var req TeamJoinRequest
+		err := rows.Scan(&req.ID, &req.Team, &req.User, &req.Message, &req.Status, &req.CreatedAt)
+		if err != nil {
+			return reqs, err
+		}
+		reqs = append(reqs, &req)
+	}
+	err = rows.Err()
+	if err != nil {
+		return reqs, err
+	}
+	return reqs, nil
+}
+
+// DecideTeamJoinRequest approves or rejects a pending join request,
+// approving adds the player to the roster in the same transaction
+func (m *SQLStore) DecideTeamJoinRequest(requestID, teamID int64, approve bool) (*TeamJoinRequest, error) {
+	req := &TeamJoinRequest{ID: requestID, Team: teamID, Status: joinRequestRejected}
+	if approve {
+		req.Status = joinRequestApproved
+	}
+	req.DecidedAt = time.Now().Unix()
+	err := m.withTx("decide join request", func(tx *sqlTx) error {
+		err := tx.QueryRow("SELECT userId, message, createdAt FROM team_join_request WHERE id=? AND teamId=? AND status=?", requestID, teamID, joinRequestPending).
+			Scan(&req.User, &req.Message, &req.CreatedAt)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				return errJoinRequestNotFound
+			}
+			return err
+		}
+		res, err := tx.Exec("UPDATE team_join_request SET status=?, decidedAt=? WHERE id=? AND status=?", req.Status, req.DecidedAt, requestID, joinRequestPending)
+		if err != nil {
+			return err
+		}
+		n, err := res.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if n == 0 {
+			return errJoinRequestNotFound
+		}
+		if approve {
+			return addToRoster(tx, req.User, teamID)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return req, nil
+}
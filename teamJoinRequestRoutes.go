@@ -0,0 +1,98 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi"
+	"github.com/go-chi/render"
+)
+
+// CreateTeamJoinRequest lets a player ask to join a team
+func (s *Server) CreateTeamJoinRequest(w http.ResponseWriter, r *http.Request) {
+	teamID, err := teamIDParam(r)
+	if err != nil {
+		render.Render(w, r, ErrBadRequest(err))
+		return
+	}
+	data := &TeamJoinRequestRequest{}
+	if err := render.Bind(r, data); err != nil {
+		render.Render(w, r, ErrBadRequest(err))
+		return
+	}
+	req := &TeamJoinRequest{Team: teamID, User: data.ProtectedID, Message: data.Message}
+	err = s.invites.NewTeamJoinRequest(req)
+	if err != nil {
+		switch {
+		case err == errTeamNotFound:
+			render.Render(w, r, ErrNotFound(err))
+		case err == errJoinRequestsClosed:
+			render.Render(w, r, ErrForbidden(err))
+		case errors.Is(err, errJoinRequestExists), errors.Is(err, errJoinRequestLimit), errors.Is(err, errAlreadyOnRoster):
+			render.Render(w, r, ErrConflict(err))
+		default:
+			render.Render(w, r, ErrDB(err))
+		}
+		return
+	}
+	render.Status(r, http.StatusCreated)
+	render.Render(w, r, NewTeamJoinRequestResponse(req))
+}
+
+// GetTeamJoinRequests renders the pending join requests of a team for its captain and co-captains
+func (s *Server) GetTeamJoinRequests(w http.ResponseWriter, r *http.Request) {
+	teamID, err := teamIDParam(r)
+	if err != nil {
+		render.Render(w, r, ErrBadRequest(err))
+		return
+	}
+	if !s.requireRosterManager(w, r, teamID) {
+		return
+	}
+	reqList, err := s.invites.GetTeamJoinRequests(teamID)
+	if err != nil {
+		render.Render(w, r, ErrDB(err))
+		return
+	}
+	if err := render.RenderList(w, r, NewTeamJoinRequestListResponse(reqList)); err != nil {
+		render.Render(w, r, ErrRender(err))
+		return
+	}
+}
+
+// ApproveTeamJoinRequest adds the requesting player to the roster
+func (s *Server) ApproveTeamJoinRequest(w http.ResponseWriter, r *http.Request) {
+	s.decideTeamJoinRequest(w, r, true)
+}
+
+// RejectTeamJoinRequest closes a join request without adding the player
+func (s *Server) RejectTeamJoinRequest(w http.ResponseWriter, r *http.Request) {
+	s.decideTeamJoinRequest(w, r, false)
+}
+
+func (s *Server) decideTeamJoinRequest(w http.ResponseWriter, r *http.Request, approve bool) {
+	teamID, err := teamIDParam(r)
+	if err != nil {
+		render.Render(w, r, ErrBadRequest(err))
+		return
+	}
+	requestID, err := strconv.ParseInt(chi.URLParam(r, "requestID"), 10, 64)
+	if err != nil {
+		render.Render(w, r, ErrBadRequest(errors.New("request id not valid")))
+		return
+	}
+	if !s.requireRosterManager(w, r, teamID) {
+		return
+	}
+	req, err := s.invites.DecideTeamJoinRequest(requestID, teamID, approve)
+	if err != nil {
+		if errors.Is(err, errJoinRequestNotFound) {
+			render.Render(w, r, ErrNotFound(err))
+			return
+		}
+		render.Render(w, r, ErrDB(err))
+		return
+	}
+	render.Render(w, r, NewTeamJoinRequestResponse(req))
+}
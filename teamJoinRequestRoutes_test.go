@@ -0,0 +1,60 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+)
+
+func TestTeamJoinRequestRoutes(t *testing.T) {
+	ts := newTestServer(t)
+	_, captain := ts.signup("captain")
+	playerID, player := ts.signup("player")
+	team := ts.createTeam(captain.Token, "Alpha Squad")
+	requests := fmt.Sprintf("/team/%d/join-requests", team.ID)
+
+	if status := ts.do("POST", requests, player.Token, map[string]string{}, nil); status != http.StatusForbidden {
+		t.Errorf("request to a closed team: status %d, want %d", status, http.StatusForbidden)
+	}
+	if status := ts.do("PATCH", fmt.Sprintf("/team/%d", team.ID), captain.Token, map[string]bool{"acceptsRequests": true}, nil); status != http.StatusOK {
+		t.Fatalf("open team: status %d", status)
+	}
+	var req TeamJoinRequest
+	if status := ts.do("POST", requests, player.Token, map[string]string{"message": "let me in"}, &req); status != http.StatusCreated {
+		t.Fatalf("request: status %d", status)
+	}
+	if req.User != playerID || req.Status != joinRequestPending {
+		t.Errorf("request = %+v", req)
+	}
+
+	tests := []struct {
+		name   string
+		token  string
+		status int
+	}{
+		{"pending request", player.Token, http.StatusConflict},
+		{"already on the roster", captain.Token, http.StatusConflict},
+		{"unauthenticated", "", http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if status := ts.do("POST", requests, tt.token, map[string]string{}, nil); status != tt.status {
+				t.Errorf("status = %d, want %d", status, tt.status)
+			}
+		})
+	}
+
+	approve := fmt.Sprintf("%s/%d/approve", requests, req.ID)
+	if status := ts.do("POST", approve, player.Token, nil, nil); status != http.StatusForbidden {
+		t.Errorf("approve as the player: status %d, want %d", status, http.StatusForbidden)
+	}
+	if status := ts.do("POST", approve, captain.Token, nil, nil); status != http.StatusOK {
+		t.Fatalf("approve: status %d", status)
+	}
+	if status := ts.do("POST", approve, captain.Token, nil, nil); status != http.StatusNotFound {
+		t.Errorf("approve twice: status %d, want %d", status, http.StatusNotFound)
+	}
+	if role, err := ts.store.GetRosterRole(playerID, team.ID); err != nil || role != roleMember {
+		t.Errorf("player role = %q, %v", role, err)
+	}
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestNewTeamJoinRequest(t *testing.T) {
+	forEachStore(t, func(t *testing.T, store Store) {
+		captain := newTestUser(t, store, "captain")
+		player := newTestUser(t, store, "player")
+		team := newTestTeam(t, store, captain)
+
+		req := &TeamJoinRequest{Team: team.ID, User: player}
+		if err := store.NewTeamJoinRequest(req); !errors.Is(err, errJoinRequestsClosed) {
+			t.Fatalf("request to a closed team: %v, want %v", err, errJoinRequestsClosed)
+		}
+		if err := store.SetTeamAcceptsRequests(team.ID, true); err != nil {
+			t.Fatal(err)
+		}
+		if err := store.NewTeamJoinRequest(req); err != nil {
+			t.Fatal(err)
+		}
+		if req.ID == 0 || req.Status != joinRequestPending {
+			t.Errorf("request = %+v", req)
+		}
+		if err := store.NewTeamJoinRequest(&TeamJoinRequest{Team: team.ID, User: player}); !errors.Is(err, errJoinRequestExists) {
+			t.Errorf("second pending request: %v, want %v", err, errJoinRequestExists)
+		}
+		if err := store.NewTeamJoinRequest(&TeamJoinRequest{Team: team.ID, User: captain}); !errors.Is(err, errAlreadyOnRoster) {
+			t.Errorf("request from the captain: %v, want %v", err, errAlreadyOnRoster)
+		}
+
+		for i := 1; i < maxOpenJoinRequests; i++ {
+			other := newTestTeam(t, store, newTestUser(t, store, fmt.Sprintf("captain%d", i)))
+			if err := store.SetTeamAcceptsRequests(other.ID, true); err != nil {
+				t.Fatal(err)
+			}
+			if err := store.NewTeamJoinRequest(&TeamJoinRequest{Team: other.ID, User: player}); err != nil {
+				t.Fatalf("request %d: %v", i+1, err)
+			}
+		}
+		last := newTestTeam(t, store, newTestUser(t, store, "lastcaptain"))
+		if err := store.SetTeamAcceptsRequests(last.ID, true); err != nil {
+			t.Fatal(err)
+		}
+		if err := store.NewTeamJoinRequest(&TeamJoinRequest{Team: last.ID, User: player}); !errors.Is(err, errJoinRequestLimit) {
+			t.Errorf("request over the limit: %v, want %v", err, errJoinRequestLimit)
+		}
+	})
+}
+
+func TestDecideTeamJoinRequest(t *testing.T) {
+	forEachStore(t, func(t *testing.T, store Store) {
+		captain := newTestUser(t, store, "captain")
+		approved := newTestUser(t, store, "approved")
+		rejected := newTestUser(t, store, "rejected")
+		team := newTestTeam(t, store, captain)
+		if err := store.SetTeamAcceptsRequests(team.ID, true); err != nil {
+			t.Fatal(err)
+		}
+		approve := &TeamJoinRequest{Team: team.ID, User: approved}
+		reject := &TeamJoinRequest{Team: team.ID, User: rejected}
+		for _, req := range []*TeamJoinRequest{approve, reject} {
+			if err := store.NewTeamJoinRequest(req); err != nil {
+				t.Fatal(err)
+			}
+		}
+		pending, err := store.GetTeamJoinRequests(team.ID)
+		if err != nil || len(pending) != 2 {
+			t.Fatalf("pending requests = %+v, %v", pending, err)
+		}
+
+		decided, err := store.DecideTeamJoinRequest(approve.ID, team.ID, true)
+		if err != nil || decided.Status != joinRequestApproved || decided.User != approved {
+			t.Fatalf("approve = %+v, %v", decided, err)
+		}
+		if role, err := store.GetRosterRole(approved, team.ID); err != nil || role != roleMember {
+			t.Errorf("approved player role = %q, %v", role, err)
+		}
+		if _, err := store.DecideTeamJoinRequest(reject.ID, team.ID, false); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := store.GetRosterRole(rejected, team.ID); !errors.Is(err, errNotOnRoster) {
+			t.Errorf("rejected player is on the roster: %v", err)
+		}
+		if _, err := store.DecideTeamJoinRequest(approve.ID, team.ID, true); !errors.Is(err, errJoinRequestNotFound) {
+			t.Errorf("deciding twice: %v, want %v", err, errJoinRequestNotFound)
+		}
+		pending, err = store.GetTeamJoinRequests(team.ID)
+		if err != nil || len(pending) != 0 {
+			t.Errorf("pending requests after deciding = %+v, %v", pending, err)
+		}
+		// a rejected player may ask again
+		if err := store.NewTeamJoinRequest(&TeamJoinRequest{Team: team.ID, User: rejected}); err != nil {
+			t.Errorf("request after a rejection: %v", err)
+		}
+	})
+}
+
+func TestPendingJoinRequestIndex(t *testing.T) {
+	// the count in NewTeamJoinRequest can race, the index has the last word
+	store := newTestSQLStore(t)
+	captain := newTestUser(t, store, "captain")
+	player := newTestUser(t, store, "player")
+	team := newTestTeam(t, store, captain)
+	insert := "INSERT INTO team_join_request(teamId,userId,message,status,createdAt) VALUES(?,?,?,?,?)"
+	now := time.Now().Unix()
+	if _, err := store.db.Exec(insert, team.ID, player, "", joinRequestRejected, now); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.db.Exec(insert, team.ID, player, "", joinRequestPending, now); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.db.Exec(insert, team.ID, player, "", joinRequestPending, now); kindOf(err) != KindConflict {
+		t.Errorf("second pending request: %v, want a conflict", err)
+	}
+}
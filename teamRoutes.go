@@ -2,42 +2,113 @@ package main
 
 import (
 	"errors"
+	"fmt"
 	"net/http"
+	"strconv"
 
 	"github.com/go-chi/chi"
 	"github.com/go-chi/render"
-	"github.com/go-sql-driver/mysql"
 )
 
+// roster page sizes, the team detail route includes up to maxRosterLimit members
+const (
+	defaultRosterLimit = 25
+	maxRosterLimit     = 100
+)
+
+// maxKickReasonLength is the size of the roster_removal.reason column
+const maxKickReasonLength = 255
+
 // TeamRoutes returns a router with the team routes to be mounted in routes.go
-func TeamRoutes() *chi.Mux {
+func (s *Server) TeamRoutes() *chi.Mux {
 	r := chi.NewRouter()
-	r.Post("/", CreateTeam)
-	r.Get("/search/{value}/{offset}", SearchTeam)
-	r.Get("/by-user/{userID}", GetUserTeams)
-	r.Put("/", ModifyRoster)
+	r.Get("/search/{value}/{offset}", s.SearchTeam)
+	r.Get("/by-user/{userID}", s.GetUserTeams)
+	r.Get("/{teamID}", s.GetTeam)
+	r.Get("/{teamID}/roster", s.GetTeamRoster)
+	r.Group(func(r chi.Router) {
+		r.Use(RequireAuth)
+		r.Post("/", s.CreateTeam)
+		r.Put("/", s.ModifyRoster)
+		r.Get("/{teamID}/invites", s.GetTeamInvites)
+		r.Post("/{teamID}/join-links", s.CreateTeamJoinLink)
+		r.Get("/{teamID}/join-links", s.GetTeamJoinLinks)
+		r.Delete("/{teamID}/join-links/{code}", s.RevokeTeamJoinLink)
+		r.Post("/join/{code}", s.RedeemTeamJoinLink)
+		r.Patch("/{teamID}", s.UpdateTeamSettings)
+		r.Post("/{teamID}/join-requests", s.CreateTeamJoinRequest)
+		r.Get("/{teamID}/join-requests", s.GetTeamJoinRequests)
+		r.Post("/{teamID}/join-requests/{requestID}/approve", s.ApproveTeamJoinRequest)
+		r.Post("/{teamID}/join-requests/{requestID}/reject", s.RejectTeamJoinRequest)
+		r.Post("/{teamID}/transfer-captain", s.TransferCaptain)
+		r.Put("/{teamID}/roster/{userID}/role", s.SetRosterRole)
+		r.Delete("/{teamID}/roster/{userID}", s.KickFromRoster)
+		r.Delete("/{teamID}", s.DisbandTeam)
+	})
+	r.Mount("/invite", s.TeamInviteRoutes())
 	return r
 }
 
-// ModifyRoster allows for adding and removing users to and from a team
-func ModifyRoster(w http.ResponseWriter, r *http.Request) {
+// teamIDParam parses the teamID url param
+func teamIDParam(r *http.Request) (int64, error) {
+	teamID, err := strconv.ParseInt(chi.URLParam(r, "teamID"), 10, 64)
+	if err != nil {
+		return 0, errors.New("team id not valid")
+	}
+	return teamID, nil
+}
+
+// requireCaptain renders an error and returns false if the requesting user is not captain of teamID
+func (s *Server) requireCaptain(w http.ResponseWriter, r *http.Request, teamID int64) bool {
+	value, err := s.teams.IsCaptain(protectedID(r), teamID)
+	if err != nil {
+		render.Render(w, r, ErrDB(err))
+		return false
+	}
+	if !value {
+		render.Render(w, r, ErrForbidden(errors.New("only captain can manage team")))
+		return false
+	}
+	return true
+}
+
+// requireRosterManager renders an error and returns false if the requesting user is not a captain or co-captain of teamID
+func (s *Server) requireRosterManager(w http.ResponseWriter, r *http.Request, teamID int64) bool {
+	value, err := s.canManageRoster(protectedID(r), teamID)
+	if err != nil {
+		render.Render(w, r, ErrDB(err))
+		return false
+	}
+	if !value {
+		render.Render(w, r, ErrForbidden(errors.New("only captain or co-captain can manage roster")))
+		return false
+	}
+	return true
+}
+
+// canManageRoster reports whether userID is a captain or co-captain of teamID
+func (s *Server) canManageRoster(userID, teamID int64) (bool, error) {
+	role, err := s.teams.GetRosterRole(userID, teamID)
+	if err != nil {
+		if err == errNotOnRoster {
+			return false, nil
+		}
+		return false, err
+	}
+	return isRosterManager(role), nil
+}
+
+// ModifyRoster lets the requesting user leave a team
+// players join a team by accepting an invite, redeeming a join link or having a join request approved
+func (s *Server) ModifyRoster(w http.ResponseWriter, r *http.Request) {
 	data := &TeamRequest{}
 	if err := render.Bind(r, data); err != nil {
 		render.Render(w, r, ErrBadRequest(err))
 		return
 	}
 	// TODO check to make sure team is not in active tournaments
-	if data.Action == "add" && data.Invitee != data.ProtectedID {
-		render.Render(w, r, ErrUnauthorized(errors.New("not intended user")))
-		return
-	}
-	err := dbEditRoster(data.Action, data.ProtectedID, data.Team.ID)
+	err := s.teams.EditRoster(data.Action, data.ProtectedID, data.Team.ID)
 	if err != nil {
-		_, ok := err.(*mysql.MySQLError)
-		if !ok {
-			render.Render(w, r, ErrForbidden(err))
-			return
-		}
 		render.Render(w, r, ErrDB(err))
 		return
 	}
@@ -45,15 +116,18 @@ func ModifyRoster(w http.ResponseWriter, r *http.Request) {
 }
 
 // GetUserTeams renders a list of all teams of which the userID is a member
-func GetUserTeams(w http.ResponseWriter, r *http.Request) {
+func (s *Server) GetUserTeams(w http.ResponseWriter, r *http.Request) {
 	var teamList []*Team
 	var err error
 	if userID := chi.URLParam(r, "userID"); userID != "" {
-		teamList, err = dbGetUserTeams(userID)
+		teamList, err = s.teams.GetUserTeams(userID)
 	} else {
 		render.Render(w, r, ErrBadRequest(errors.New("user id not valid")))
 		return
 	}
+	if err == nil {
+		err = s.attachAverageRanks(teamList)
+	}
 	if err != nil {
 		render.Render(w, r, ErrDB(err))
 		return
@@ -65,16 +139,23 @@ func GetUserTeams(w http.ResponseWriter, r *http.Request) {
 }
 
 // SearchTeam searches for teams with name values starting with the search value
-func SearchTeam(w http.ResponseWriter, r *http.Request) {
+func (s *Server) SearchTeam(w http.ResponseWriter, r *http.Request) {
 	var err error
 	var teamList []*Team
-	offset := chi.URLParam(r, "offset")
+	offset, err := offsetParam(r)
+	if err != nil {
+		render.Render(w, r, ErrBadRequest(err))
+		return
+	}
 	if searchValue := chi.URLParam(r, "value"); searchValue != "" {
-		teamList, err = dbSearchTeamName(searchValue, offset)
+		teamList, err = s.teams.SearchTeamName(searchValue, offset)
 	} else {
 		render.Render(w, r, ErrBadRequest(errors.New("search value empty")))
 		return
 	}
+	if err == nil {
+		err = s.attachAverageRanks(teamList)
+	}
 	if err != nil {
 		render.Render(w, r, ErrDB(err))
 		return
@@ -86,7 +167,7 @@ func SearchTeam(w http.ResponseWriter, r *http.Request) {
 }
 
 // CreateTeam creates a team in the database
-func CreateTeam(w http.ResponseWriter, r *http.Request) {
+func (s *Server) CreateTeam(w http.ResponseWriter, r *http.Request) {
 	data := &TeamRequest{}
 	if err := render.Bind(r, data); err != nil {
 		render.Render(w, r, ErrBadRequest(err))
@@ -94,15 +175,262 @@ func CreateTeam(w http.ResponseWriter, r *http.Request) {
 	}
 	team := data.Team
 	team.Captain = data.ProtectedID
-	id, err := dbNewTeam(team)
+	err := s.teams.CreateTeam(team)
 	if err != nil {
 		render.Render(w, r, ErrDB(err))
 		return
 	}
-	err = dbAddToRoster(data.ProtectedID, id)
+	render.Render(w, r, NewTeamResponse(team))
+}
+
+// UpdateTeamSettings changes the settings of a team
+func (s *Server) UpdateTeamSettings(w http.ResponseWriter, r *http.Request) {
+	teamID, err := teamIDParam(r)
 	if err != nil {
-		// need to retry adding to roster or delete created team and give error
+		render.Render(w, r, ErrBadRequest(err))
+		return
+	}
+	data := &TeamSettingsRequest{}
+	if err := render.Bind(r, data); err != nil {
+		render.Render(w, r, ErrBadRequest(err))
+		return
+	}
+	if !s.requireCaptain(w, r, teamID) {
+		return
+	}
+	err = s.teams.SetTeamAcceptsRequests(teamID, *data.AcceptsRequests)
+	if err != nil {
+		render.Render(w, r, ErrDB(err))
+		return
+	}
+	render.Render(w, r, NewTeamResponse(&Team{ID: teamID, AcceptsRequests: *data.AcceptsRequests}))
+}
+
+// TransferCaptain hands captaincy of a team to another roster member
+func (s *Server) TransferCaptain(w http.ResponseWriter, r *http.Request) {
+	teamID, err := teamIDParam(r)
+	if err != nil {
+		render.Render(w, r, ErrBadRequest(err))
+		return
+	}
+	data := &TransferCaptainRequest{}
+	if err := render.Bind(r, data); err != nil {
+		render.Render(w, r, ErrBadRequest(err))
+		return
+	}
+	if data.NewCaptain == data.ProtectedID {
+		render.Render(w, r, ErrBadRequest(errSameCaptain))
+		return
+	}
+	if !s.requireCaptain(w, r, teamID) {
+		return
+	}
+	err = s.teams.TransferCaptain(teamID, data.ProtectedID, data.NewCaptain)
+	if err != nil {
+		render.Render(w, r, ErrDB(err))
+		return
+	}
+	render.Render(w, r, NewTeamResponse(&Team{ID: teamID, Captain: data.NewCaptain}))
+}
+
+// SetRosterRole changes the role of a roster member
+// captains can assign any role but captain, co-captains can only move
+// players between member and substitute
+func (s *Server) SetRosterRole(w http.ResponseWriter, r *http.Request) {
+	teamID, err := teamIDParam(r)
+	if err != nil {
+		render.Render(w, r, ErrBadRequest(err))
+		return
+	}
+	userID, err := strconv.ParseInt(chi.URLParam(r, "userID"), 10, 64)
+	if err != nil {
+		render.Render(w, r, ErrBadRequest(errors.New("user id not valid")))
+		return
+	}
+	data := &RosterRoleRequest{}
+	if err := render.Bind(r, data); err != nil {
+		render.Render(w, r, ErrBadRequest(err))
+		return
+	}
+	actorRole, err := s.teams.GetRosterRole(data.ProtectedID, teamID)
+	if err != nil && err != errNotOnRoster {
+		render.Render(w, r, ErrDB(err))
+		return
+	}
+	if !isRosterManager(actorRole) {
+		render.Render(w, r, ErrForbidden(errors.New("only captain or co-captain can manage roster")))
+		return
+	}
+	targetRole, err := s.teams.GetRosterRole(userID, teamID)
+	if err != nil {
+		if err == errNotOnRoster {
+			render.Render(w, r, ErrNotFound(err))
+			return
+		}
+		render.Render(w, r, ErrDB(err))
+		return
+	}
+	if targetRole == roleCaptain {
+		render.Render(w, r, ErrForbidden(errors.New("captain role can only be changed by transferring captaincy")))
+		return
+	}
+	if actorRole == roleCoCaptain && (data.Role == roleCoCaptain || targetRole == roleCoCaptain) {
+		render.Render(w, r, ErrForbidden(errors.New("only captain can manage co-captains")))
+		return
+	}
+	err = s.teams.SetRosterRole(userID, teamID, data.Role)
+	if err != nil {
+		render.Render(w, r, ErrDB(err))
+		return
+	}
+	render.NoContent(w, r)
+}
+
+// KickFromRoster removes another player from a team
+// an optional reason can be given in the reason query param
+func (s *Server) KickFromRoster(w http.ResponseWriter, r *http.Request) {
+	teamID, err := teamIDParam(r)
+	if err != nil {
+		render.Render(w, r, ErrBadRequest(err))
+		return
+	}
+	userID, err := strconv.ParseInt(chi.URLParam(r, "userID"), 10, 64)
+	if err != nil {
+		render.Render(w, r, ErrBadRequest(errors.New("user id not valid")))
+		return
+	}
+	reason := r.URL.Query().Get("reason")
+	if len(reason) > maxKickReasonLength {
+		render.Render(w, r, ErrBadRequest(errors.New("reason is too long")))
+		return
+	}
+	actorID := protectedID(r)
+	if userID == actorID {
+		render.Render(w, r, ErrBadRequest(errors.New("use the roster remove action to leave a team")))
+		return
+	}
+	actorRole, err := s.teams.GetRosterRole(actorID, teamID)
+	if err != nil && err != errNotOnRoster {
+		render.Render(w, r, ErrDB(err))
+		return
+	}
+	if !isRosterManager(actorRole) {
+		render.Render(w, r, ErrForbidden(errors.New("only captain or co-captain can manage roster")))
+		return
+	}
+	targetRole, err := s.teams.GetRosterRole(userID, teamID)
+	if err != nil {
+		if err == errNotOnRoster {
+			render.Render(w, r, ErrNotFound(err))
+			return
+		}
+		render.Render(w, r, ErrDB(err))
+		return
+	}
+	if targetRole == roleCaptain || (actorRole == roleCoCaptain && targetRole == roleCoCaptain) {
+		render.Render(w, r, ErrForbidden(errors.New("cannot remove a player with an equal or higher role")))
+		return
+	}
+	err = s.teams.KickFromRoster(userID, teamID, actorID, reason)
+	if err != nil {
+		if errors.Is(err, errNotOnRoster) {
+			render.Render(w, r, ErrNotFound(err))
+			return
+		}
+		render.Render(w, r, ErrDB(err))
+		return
+	}
+	render.NoContent(w, r)
+}
+
+// DisbandTeam disbands a team at the request of its captain, clearing its roster
+// the team is kept as a tombstone with disbandedAt set
+func (s *Server) DisbandTeam(w http.ResponseWriter, r *http.Request) {
+	teamID, err := teamIDParam(r)
+	if err != nil {
+		render.Render(w, r, ErrBadRequest(err))
+		return
+	}
+	if !s.requireCaptain(w, r, teamID) {
+		return
+	}
+	err = s.teams.DisbandTeam(teamID)
+	if err != nil {
+		if errors.Is(err, errTeamNotFound) {
+			render.Render(w, r, ErrNotFound(err))
+			return
+		}
+		render.Render(w, r, ErrDB(err))
+		return
+	}
+	render.NoContent(w, r)
+}
+
+// GetTeam renders a team along with its roster
+func (s *Server) GetTeam(w http.ResponseWriter, r *http.Request) {
+	teamID, err := teamIDParam(r)
+	if err != nil {
+		render.Render(w, r, ErrBadRequest(err))
+		return
+	}
+	team, err := s.teams.GetTeam(teamID)
+	if err != nil {
+		if err == errTeamNotFound {
+			render.Render(w, r, ErrNotFound(err))
+			return
+		}
+		render.Render(w, r, ErrDB(err))
+		return
+	}
+	roster, err := s.teams.GetTeamRoster(teamID, maxRosterLimit, 0)
+	if err != nil {
+		render.Render(w, r, ErrDB(err))
+		return
+	}
+	if err := s.attachAverageRanks([]*Team{team}); err != nil {
+		render.Render(w, r, ErrDB(err))
+		return
+	}
+	render.Render(w, r, NewTeamDetailResponse(team, roster))
+}
+
+// GetTeamRoster renders a page of a team roster using the limit and offset query params
+func (s *Server) GetTeamRoster(w http.ResponseWriter, r *http.Request) {
+	teamID, err := teamIDParam(r)
+	if err != nil {
+		render.Render(w, r, ErrBadRequest(err))
+		return
+	}
+	limit, offset := defaultRosterLimit, 0
+	if v := r.URL.Query().Get("limit"); v != "" {
+		limit, err = strconv.Atoi(v)
+		if err != nil || limit < 1 || limit > maxRosterLimit {
+			render.Render(w, r, ErrBadRequest(fmt.Errorf("limit must be between 1 and %d", maxRosterLimit)))
+			return
+		}
+	}
+	if v := r.URL.Query().Get("offset"); v != "" {
+		offset, err = strconv.Atoi(v)
+		if err != nil || offset < 0 {
+			render.Render(w, r, ErrBadRequest(errors.New("offset not valid")))
+			return
+		}
+	}
+	if _, err := s.teams.GetTeam(teamID); err != nil {
+		if errors.Is(err, errTeamNotFound) {
+			render.Render(w, r, ErrNotFound(err))
+			return
+		}
+		render.Render(w, r, ErrDB(err))
+		return
+	}
+	roster, err := s.teams.GetTeamRoster(teamID, limit, offset)
+	if err != nil {
+		render.Render(w, r, ErrDB(err))
+		return
+	}
+	if err := render.RenderList(w, r, NewRosterListResponse(roster)); err != nil {
+		render.Render(w, r, ErrRender(err))
+		return
 	}
-	team.ID = id
-	render.Render(w, r, NewTeamResponse(team))
 }
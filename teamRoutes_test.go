@@ -0,0 +1,178 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestCreateTeam(t *testing.T) {
+	ts := newTestServer(t)
+	captainID, tokens := ts.signup("captain")
+
+	team := ts.createTeam(tokens.Token, "Alpha Squad")
+	if team.ID == 0 || team.Captain != captainID || team.Region != "na1" {
+		t.Fatalf("created team = %+v", team)
+	}
+	var detail TeamDetailResponse
+	if status := ts.do("GET", fmt.Sprintf("/team/%d", team.ID), "", nil, &detail); status != http.StatusOK {
+		t.Fatalf("get team: status %d", status)
+	}
+	if len(detail.Roster) != 1 || detail.Roster[0].UserID != captainID || detail.Roster[0].Role != roleCaptain {
+		t.Errorf("roster = %+v, want only the captain", detail.Roster)
+	}
+
+	tests := []struct {
+		name   string
+		token  string
+		body   interface{}
+		status int
+	}{
+		{"unauthenticated", "", map[string]string{"name": "Beta Squad"}, http.StatusUnauthorized},
+		{"short name", tokens.Token, map[string]string{"name": "ab"}, http.StatusUnprocessableEntity},
+		{"unknown region", tokens.Token, map[string]string{"name": "Beta Squad", "region": "moon1"}, http.StatusUnprocessableEntity},
+		{"missing fields", tokens.Token, nil, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if status := ts.do("POST", "/team", tt.token, tt.body, nil); status != tt.status {
+				t.Errorf("status = %d, want %d", status, tt.status)
+			}
+		})
+	}
+}
+
+func TestSearchTeam(t *testing.T) {
+	ts := newTestServer(t)
+	_, captain := ts.signup("captain")
+	ts.createTeam(captain.Token, "Alpha Squad")
+	ts.createTeam(captain.Token, "Alpine Five")
+
+	tests := []struct {
+		name   string
+		path   string
+		status int
+		count  int
+	}{
+		{"first page", "/team/search/alp/0", http.StatusOK, 2},
+		{"second result", "/team/search/alp/1", http.StatusOK, 1},
+		{"negative offset", "/team/search/alp/-1", http.StatusBadRequest, 0},
+		{"offset not a number", "/team/search/alp/x", http.StatusBadRequest, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var teams []*Team
+			if status := ts.do("GET", tt.path, "", nil, &teams); status != tt.status {
+				t.Fatalf("status = %d, want %d", status, tt.status)
+			}
+			if tt.status == http.StatusOK && len(teams) != tt.count {
+				t.Errorf("found %d teams, want %d", len(teams), tt.count)
+			}
+		})
+	}
+}
+
+func TestModifyRoster(t *testing.T) {
+	ts := newTestServer(t)
+	_, captain := ts.signup("captain")
+	playerID, player := ts.signup("player")
+	team := ts.createTeam(captain.Token, "Alpha Squad")
+
+	var invite TeamInvite
+	body := map[string]int64{"teamId": team.ID, "invitee": playerID}
+	if status := ts.do("POST", "/team/invite", captain.Token, body, &invite); status != http.StatusOK {
+		t.Fatalf("invite: status %d", status)
+	}
+	if status := ts.do("POST", fmt.Sprintf("/team/invite/%d/accept", invite.ID), player.Token, nil, nil); status != http.StatusOK {
+		t.Fatalf("accept: status %d", status)
+	}
+
+	leave := map[string]interface{}{"teamId": team.ID, "action": "remove"}
+	tests := []struct {
+		name   string
+		token  string
+		body   interface{}
+		status int
+	}{
+		{"add is not an action", player.Token, map[string]interface{}{"teamId": team.ID, "action": "add"}, http.StatusUnprocessableEntity},
+		{"captain cannot leave", captain.Token, leave, http.StatusForbidden},
+		{"player leaves", player.Token, leave, http.StatusOK},
+		{"player already left", player.Token, leave, http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if status := ts.do("PUT", "/team", tt.token, tt.body, nil); status != tt.status {
+				t.Errorf("status = %d, want %d", status, tt.status)
+			}
+		})
+	}
+
+	roster, err := ts.store.GetTeamRoster(team.ID, 10, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(roster) != 1 || roster[0].UserID == playerID {
+		t.Errorf("roster after leaving = %+v", roster)
+	}
+}
+
+func TestKickFromRoster(t *testing.T) {
+	ts := newTestServer(t)
+	_, captain := ts.signup("captain")
+	playerID, player := ts.signup("player")
+	team := ts.createTeam(captain.Token, "Alpha Squad")
+	var link TeamJoinLink
+	if status := ts.do("POST", fmt.Sprintf("/team/%d/join-links", team.ID), captain.Token, map[string]int{}, &link); status != http.StatusCreated {
+		t.Fatalf("create link: status %d", status)
+	}
+	if status := ts.do("POST", "/team/join/"+link.Code, player.Token, nil, nil); status != http.StatusOK {
+		t.Fatalf("redeem: status %d", status)
+	}
+
+	kick := fmt.Sprintf("/team/%d/roster/%d", team.ID, playerID)
+	tests := []struct {
+		name   string
+		path   string
+		token  string
+		status int
+	}{
+		{"reason too long", kick + "?reason=" + strings.Repeat("x", maxKickReasonLength+1), captain.Token, http.StatusBadRequest},
+		{"by a player", fmt.Sprintf("/team/%d/roster/%d", team.ID, 1), player.Token, http.StatusForbidden},
+		{"the player", kick + "?reason=afk", captain.Token, http.StatusNoContent},
+		{"a player not on the roster", kick, captain.Token, http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if status := ts.do("DELETE", tt.path, tt.token, nil, nil); status != tt.status {
+				t.Errorf("status = %d, want %d", status, tt.status)
+			}
+		})
+	}
+}
+
+func TestDisbandTeam(t *testing.T) {
+	ts := newTestServer(t)
+	_, captain := ts.signup("captain")
+	_, player := ts.signup("player")
+	team := ts.createTeam(captain.Token, "Alpha Squad")
+	path := fmt.Sprintf("/team/%d", team.ID)
+
+	if status := ts.do("DELETE", path, player.Token, nil, nil); status != http.StatusForbidden {
+		t.Errorf("disband as a player: status %d, want %d", status, http.StatusForbidden)
+	}
+	if status := ts.do("DELETE", path, captain.Token, nil, nil); status != http.StatusNoContent {
+		t.Fatalf("disband: status %d", status)
+	}
+	var detail TeamDetailResponse
+	if status := ts.do("GET", path, "", nil, &detail); status != http.StatusOK {
+		t.Fatalf("get disbanded team: status %d", status)
+	}
+	if detail.DisbandedAt == 0 || detail.Name != team.Name || len(detail.Roster) != 0 {
+		t.Errorf("disbanded team = %+v, roster %+v", detail.Team, detail.Roster)
+	}
+	if err := ts.store.DisbandTeam(team.ID); !errors.Is(err, errTeamNotFound) {
+		t.Errorf("disband twice: %v, want %v", err, errTeamNotFound)
+	}
+}
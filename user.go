@@ -1,33 +1,45 @@
 package main
 
 import (
-	"encoding/json"
+	"database/sql"
 	"errors"
 	"fmt"
-	"io/ioutil"
 	"net/http"
-	"os"
-	"strconv"
-	"strings"
 
+	"github.com/anthonyrouseau/lss-api/riot"
 	"github.com/go-chi/render"
 )
 
 // User represents a user in the database
+// the password is checked against the server's password policy rather than validate rules
 type User struct {
-	ID         int64  `json:"id,omitempty"`
-	Username   string `json:"username,omitempty"`
-	Password   string `json:"password,omitempty"`
-	Email      string `json:"email,omitempty"`
-	SummonerID int    `json:"summonerId,omitempty"`
+	ID       int64  `json:"id,omitempty"`
+	Username string `json:"username,omitempty" validate:"required,min=3,max=24,charset=username,notreserved,clean"`
+	Password string `json:"password,omitempty"`
+	Email    string `json:"email,omitempty" validate:"required,max=254,email"`
+	// Accounts are the game accounts linked to the user on each region
+	Accounts []*LinkedAccount `json:"accounts,omitempty"`
+	// Rank is the best solo queue rank among the primary accounts of the user
+	Rank *Rank `json:"rank,omitempty"`
 }
 
+var (
+	errUserNotFound         = newError(KindNotFound, CodeUserNotFound, "user not found")
+	errSummonerNotFound     = newError(KindValidation, CodeSummonerNotFound, "summoner not found")
+	errSummonerCodeMismatch = newError(KindValidation, CodeSummonerCodeMismatch, "third party code does not match")
+)
+
 // UserRequest represents a request to user routes
 type UserRequest struct {
 	*User
-	ProtectedID  int64  `json:"id"`
-	SummonerName string `json:"summonerName"`
-	Code         string `json:"code"`
+	ProtectedID int64 `json:"id"`
+	// RiotID is an account to link written gameName#tagLine, on the platform in Region
+	// it is optional, accounts can be linked later through /user/me/accounts
+	RiotID   string        `json:"riotId"`
+	Region   string        `json:"region"`
+	Code     string        `json:"code"`
+	Platform riot.Platform `json:"-"`
+	riotID   riot.RiotID
 }
 
 // UserResponse represents response from user routes
@@ -35,19 +47,25 @@ type UserResponse struct {
 	*User
 }
 
-// RiotResponse represents a response from riot api
-type RiotResponse struct {
-	SummonerID int `json:"id"`
-}
-
 // Bind allows for preprocessing of user requests
 func (u *UserRequest) Bind(r *http.Request) error {
 	if u.User == nil {
 		return errors.New("missing user fields")
 	}
-
-	u.ProtectedID = -1 //reset protected Id
-	return nil
+	v := &ValidationError{}
+	rules := rulesFromRequest(r)
+	rules.Validate(u.User, v)
+	if err := rules.Passwords.Check(u.Password); err != nil {
+		v.Add("password", err.Error())
+	}
+	u.Platform, u.riotID = bindRiotAccount(u.Region, u.RiotID, v)
+	if u.RiotID != "" && u.Code == "" {
+		v.Add("code", "is required to link a summoner")
+	}
+	u.Accounts = nil
+	u.Rank = nil
+	u.ProtectedID = protectedID(r)
+	return v.Err()
 }
 
 // NewUserResponse creates a user response from user
@@ -77,67 +95,24 @@ func NewUserListResponse(users []*User) []render.Renderer {
 	return list
 }
 
-func checkSummonerID(summonerName, code string) (int, error) {
-	client := &http.Client{}
-	req, err := http.NewRequest("GET", "https://na1.api.riotgames.com/lol/summoner/v3/summoners/by-name/"+summonerName, nil)
-	if err != nil {
-		return 0, err
-	}
-	req.Header.Add("X-Riot-Token", os.Getenv("riotapikey"))
-	resp, err := client.Do(req)
-	if err != nil {
-		return 0, err
-	}
-	defer resp.Body.Close()
-	if resp.StatusCode != 200 {
-		return 0, errors.New("could not find summoner")
-	}
-	body, err := ioutil.ReadAll(resp.Body)
-	if err != nil {
-		return 0, err
-	}
-	summonerInfo := &RiotResponse{}
-	err = json.Unmarshal(body, summonerInfo)
-	if err != nil {
-		return 0, err
-	}
-	req, err = http.NewRequest("GET", "https://na1.api.riotgames.com/lol/platform/v3/third-party-code/by-summoner/"+strconv.Itoa(summonerInfo.SummonerID), nil)
-	if err != nil {
-		return 0, err
-	}
-	req.Header.Add("X-Riot-Token", os.Getenv("riotapikey"))
-	resp, err = client.Do(req)
-	if err != nil {
-		return 0, err
-	}
-	defer resp.Body.Close()
-	if resp.StatusCode != 200 {
-		return 0, errors.New("code does not match")
-	}
-	var userCode string
-	// body here is just a string so probably don't need to json.Unmarshal
-	body, err = ioutil.ReadAll(resp.Body)
-	err = json.Unmarshal(body, &userCode)
-	if err != nil {
-		return 0, err
-	}
-	if strings.Compare(userCode, code) != 0 {
-		return 0, errors.New("code does not match")
-	}
-	return summonerInfo.SummonerID, nil
-}
-
-func dbNewUser(user *User) (int64, error) {
-	//create new user in database
-	stmt, err := db.Prepare("INSERT INTO account(username,password,email,summonerId) VALUES(?,?,?,?)")
-	if err != nil {
-		return 0, err
-	}
-	res, err := stmt.Exec(user.Username, user.Password, user.Email, user.SummonerID)
-	if err != nil {
-		return 0, err
-	}
-	id, err := res.LastInsertId()
+// NewUser creates a user in the database along with its linked accounts,
+// user.Password must already be hashed
+func (m *SQLStore) NewUser(user *User) (int64, error) {
+	var id int64
+	err := m.withTx("new user", func(tx *sqlTx) error {
+		var err error
+		id, err = tx.insertID("INSERT INTO account(username,password,email) VALUES(?,?,?)", user.Username, user.Password, user.Email)
+		if err != nil {
+			return err
+		}
+		for _, account := range user.Accounts {
+			account.UserID = id
+			if err := insertLinkedAccount(tx, account); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
 	if err != nil {
 		return 0, err
 	}
@@ -145,16 +120,17 @@ func dbNewUser(user *User) (int64, error) {
 	return id, nil
 }
 
-func dbSearchUsername(searchValue string, offset string) ([]*User, error) {
+// SearchUsername returns a page of users with usernames starting with searchValue
+func (m *SQLStore) SearchUsername(searchValue string, offset int) ([]*User, error) {
 	var users []*User
-	rows, err := db.Query("SELECT id, username, summonerId FROM account WHERE username LIKE CONCAT(?,'%') LIMIT 10 OFFSET ?", searchValue, offset)
+	rows, err := m.db.Query("SELECT id, username FROM account WHERE username {startswith} LIMIT 10 OFFSET ?", searchValue, offset)
 	if err != nil {
 		return users, err
 	}
 	defer rows.Close()
 	for rows.Next() {
 		var user User
-		err := rows.Scan(&user.ID, &user.Username, &user.SummonerID)
+		err := rows.Scan(&user.ID, &user.Username)
 		if err != nil {
 			return users, err
 		}
@@ -166,3 +142,17 @@ func dbSearchUsername(searchValue string, offset string) ([]*User, error) {
 	}
 	return users, nil
 }
+
+// GetCredentials returns the ID and stored password of username or errUserNotFound
+func (m *SQLStore) GetCredentials(username string) (int64, string, error) {
+	var id int64
+	var stored string
+	err := m.db.QueryRow("SELECT id, password FROM account WHERE {usernameis}", username).Scan(&id, &stored)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return 0, "", errUserNotFound
+		}
+		return 0, "", err
+	}
+	return id, stored, nil
+}
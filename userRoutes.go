@@ -3,30 +3,123 @@ package main
 import (
 	"errors"
 	"net/http"
+	"strconv"
+	"time"
 
 	"github.com/go-chi/chi"
 	"github.com/go-chi/render"
 )
 
 // UserRoutes returns router with user routes to be mounted in routes.go
-func UserRoutes() *chi.Mux {
+func (s *Server) UserRoutes() *chi.Mux {
 	r := chi.NewRouter()
-	r.Post("/", CreateUser)
-	r.Get("/search/{value}/{offset}", SearchUser)
+	r.Post("/", s.CreateUser)
+	r.Get("/search/{value}/{offset}", s.SearchUser)
+	r.With(RequireAuth).Get("/{userID}/accounts", s.GetUserAccounts)
+	r.Get("/{userID}/rank-history", s.GetRankHistory)
+	r.Route("/me/accounts", func(r chi.Router) {
+		r.Use(RequireAuth)
+		r.Get("/", s.GetMyAccounts)
+		r.Post("/", s.StartSummonerLink)
+		r.Post("/verify", s.VerifySummonerLink)
+		r.Put("/{accountID}/primary", s.SetPrimaryAccount)
+		r.Delete("/{accountID}", s.UnlinkAccount)
+	})
+	// kept for clients that link a single summoner
+	r.Route("/me/summoner", func(r chi.Router) {
+		r.Use(RequireAuth)
+		r.Post("/", s.StartSummonerLink)
+		r.Post("/verify", s.VerifySummonerLink)
+		r.Delete("/", s.UnlinkSummoner)
+	})
+	r.Route("/sessions", func(r chi.Router) {
+		r.Use(RequireAuth)
+		r.Get("/", s.GetSessions)
+		r.Delete("/", s.RevokeOtherSessions)
+		r.Delete("/{sessionID}", s.RevokeSession)
+	})
 	return r
 }
 
+// requireSelf renders an error and returns false if the userID url param is not the requesting user
+func requireSelf(w http.ResponseWriter, r *http.Request) bool {
+	userID, err := strconv.ParseInt(chi.URLParam(r, "userID"), 10, 64)
+	if err != nil {
+		render.Render(w, r, ErrBadRequest(errors.New("user id not valid")))
+		return false
+	}
+	if userID != protectedID(r) {
+		render.Render(w, r, ErrForbidden(errors.New("only available to the user themselves")))
+		return false
+	}
+	return true
+}
+
+// offsetParam parses the offset url param of a search page
+func offsetParam(r *http.Request) (int, error) {
+	offset, err := strconv.Atoi(chi.URLParam(r, "offset"))
+	if err != nil || offset < 0 {
+		return 0, newError(KindValidation, CodeOffsetInvalid, "offset not valid")
+	}
+	return offset, nil
+}
+
+// GetSessions renders the active sessions of the authenticated user
+func (s *Server) GetSessions(w http.ResponseWriter, r *http.Request) {
+	currentID, _ := SessionIDFromContext(r.Context())
+	sessions, err := s.users.GetUserSessions(protectedID(r))
+	if err != nil {
+		render.Render(w, r, ErrDB(err))
+		return
+	}
+	if err := render.RenderList(w, r, NewSessionListResponse(sessions, currentID)); err != nil {
+		render.Render(w, r, ErrRender(err))
+		return
+	}
+}
+
+// RevokeOtherSessions revokes every session of the authenticated user except the current one
+func (s *Server) RevokeOtherSessions(w http.ResponseWriter, r *http.Request) {
+	currentID, _ := SessionIDFromContext(r.Context())
+	if err := s.users.RevokeOtherSessions(protectedID(r), currentID); err != nil {
+		render.Render(w, r, ErrDB(err))
+		return
+	}
+	render.NoContent(w, r)
+}
+
+// RevokeSession revokes one session of the authenticated user
+func (s *Server) RevokeSession(w http.ResponseWriter, r *http.Request) {
+	err := s.users.RevokeSession(chi.URLParam(r, "sessionID"), protectedID(r))
+	if err != nil {
+		if err == errSessionMissing {
+			render.Render(w, r, ErrNotFound(err))
+			return
+		}
+		render.Render(w, r, ErrDB(err))
+		return
+	}
+	render.NoContent(w, r)
+}
+
 // SearchUser searches for a user with username starting with given value
-func SearchUser(w http.ResponseWriter, r *http.Request) {
+func (s *Server) SearchUser(w http.ResponseWriter, r *http.Request) {
 	var err error
 	var userList []*User
-	offset := chi.URLParam(r, "offset")
+	offset, err := offsetParam(r)
+	if err != nil {
+		render.Render(w, r, ErrBadRequest(err))
+		return
+	}
 	if searchValue := chi.URLParam(r, "value"); searchValue != "" {
-		userList, err = dbSearchUsername(searchValue, offset)
+		userList, err = s.users.SearchUsername(searchValue, offset)
 	} else {
 		render.Render(w, r, ErrBadRequest(errors.New("search value empty")))
 		return
 	}
+	if err == nil {
+		err = s.attachUserRanks(userList)
+	}
 	if err != nil {
 		render.Render(w, r, ErrDB(err))
 		return
@@ -38,20 +131,43 @@ func SearchUser(w http.ResponseWriter, r *http.Request) {
 }
 
 // CreateUser creates a user in the databse
-func CreateUser(w http.ResponseWriter, r *http.Request) {
+func (s *Server) CreateUser(w http.ResponseWriter, r *http.Request) {
 	data := &UserRequest{}
 	if err := render.Bind(r, data); err != nil {
 		render.Render(w, r, ErrBadRequest(err))
 		return
 	}
 	user := data.User
-	summonerID, err := checkSummonerID(data.SummonerName, data.Code)
+	if data.RiotID != "" {
+		summoner, err := s.resolveSummoner(r.Context(), data.Platform, data.riotID)
+		if err == nil {
+			err = s.checkThirdPartyCode(r.Context(), data.Platform, summoner.ID, data.Code)
+		}
+		if err != nil {
+			render.Render(w, r, ErrRiot(err))
+			return
+		}
+		if err := s.checkSummonerFree(summoner.PUUID, 0); err != nil {
+			render.Render(w, r, ErrDB(err))
+			return
+		}
+		now := time.Now().Unix()
+		user.Accounts = []*LinkedAccount{{
+			PUUID:      summoner.PUUID,
+			SummonerID: summoner.ID,
+			Region:     string(data.Platform),
+			RiotID:     data.riotID.String(),
+			VerifiedAt: now,
+			CreatedAt:  now,
+		}}
+	}
+	var err error
+	user.Password, err = s.rules.Passwords.Hash(user.Password)
 	if err != nil {
-		render.Render(w, r, ErrAccountLink(err))
+		render.Render(w, r, ErrRender(err))
 		return
 	}
-	user.SummonerID = summonerID
-	id, err := dbNewUser(user)
+	id, err := s.users.NewUser(user)
 	if err != nil {
 		render.Render(w, r, ErrDB(err))
 		return
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/anthonyrouseau/lss-api/riot"
+)
+
+func TestSearchUser(t *testing.T) {
+	ts := newTestServer(t)
+	for _, name := range []string{"player", "playmaker", "jungler"} {
+		ts.signup(name)
+	}
+
+	tests := []struct {
+		name   string
+		path   string
+		status int
+		count  int
+	}{
+		{"first page", "/user/search/PLAY/0", http.StatusOK, 2},
+		{"past the end", "/user/search/play/10", http.StatusOK, 0},
+		{"negative offset", "/user/search/play/-1", http.StatusBadRequest, 0},
+		{"offset not a number", "/user/search/play/next", http.StatusBadRequest, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var users []*User
+			if status := ts.do("GET", tt.path, "", nil, &users); status != tt.status {
+				t.Fatalf("status = %d, want %d", status, tt.status)
+			}
+			if tt.status == http.StatusOK && len(users) != tt.count {
+				t.Errorf("found %d users, want %d", len(users), tt.count)
+			}
+		})
+	}
+}
+
+func TestCreateUserLinksSummoner(t *testing.T) {
+	ts := newTestServer(t)
+	ts.riot.AddSummoner(riot.EUW1, &riot.Account{PUUID: "puuid-1", GameName: "Baguette Gank", TagLine: "EUW"}, &riot.Summoner{ID: "summoner-1"}, "lss-code")
+
+	signup := func(username, riotID, code string) map[string]string {
+		return map[string]string{"username": username, "password": "correct horse battery", "email": username + "@example.com",
+			"riotId": riotID, "region": "euw1", "code": code}
+	}
+	tests := []struct {
+		name   string
+		body   map[string]string
+		riot   error
+		status int
+	}{
+		{"code required", signup("nocode", "baguette gank#euw", ""), nil, http.StatusUnprocessableEntity},
+		{"unknown riot id", signup("unknown", "nobody#euw", "lss-code"), nil, http.StatusBadRequest},
+		{"wrong code", signup("wrongcode", "baguette gank#euw", "other"), nil, http.StatusBadRequest},
+		{"riot rate limited", signup("limited", "baguette gank#euw", "lss-code"), riot.ErrRateLimited, http.StatusServiceUnavailable},
+		{"riot unavailable", signup("down", "baguette gank#euw", "lss-code"), riot.ErrUnavailable, http.StatusBadGateway},
+		{"linked", signup("linked", "baguette gank#euw", "lss-code"), nil, http.StatusOK},
+		{"linked to another user", signup("second", "Baguette Gank#EUW", "lss-code"), nil, http.StatusConflict},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ts.riot.Err = tt.riot
+			defer func() { ts.riot.Err = nil }()
+			if status := ts.do("POST", "/user", "", tt.body, nil); status != tt.status {
+				t.Errorf("status = %d, want %d", status, tt.status)
+			}
+		})
+	}
+
+	userID, _, err := ts.store.GetCredentials("linked")
+	if err != nil {
+		t.Fatal(err)
+	}
+	accounts, err := ts.store.GetLinkedAccounts(userID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(accounts) != 1 || accounts[0].PUUID != "puuid-1" || accounts[0].SummonerID != "summoner-1" ||
+		accounts[0].Region != "euw1" || !strings.EqualFold(accounts[0].RiotID, "Baguette Gank#EUW") || !accounts[0].Primary {
+		t.Errorf("linked accounts = %+v", accounts)
+	}
+}
@@ -0,0 +1,249 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net/http"
+	"net/mail"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// wordList is a set of lowercase words
+type wordList map[string]struct{}
+
+// reservedNames may not be used as usernames or team names
+var reservedNames = newWordList(
+	"admin", "administrator", "moderator", "mod", "root", "system", "support",
+	"staff", "official", "lss", "api", "auth", "team", "user", "me", "null",
+	"undefined", "riot", "riotgames",
+)
+
+func newWordList(words ...string) wordList {
+	l := wordList{}
+	for _, w := range words {
+		l[strings.ToLower(w)] = struct{}{}
+	}
+	return l
+}
+
+// Load reads a file of words into the list, one per line, skipping blank lines and # comments
+func (l wordList) Load(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		l[strings.ToLower(line)] = struct{}{}
+	}
+	return scanner.Err()
+}
+
+func (l wordList) contains(word string) bool {
+	_, ok := l[strings.ToLower(word)]
+	return ok
+}
+
+// matches reports whether any word of s is in the list, or s is a listed word
+// with separators or digits put between its letters
+func (l wordList) matches(s string) bool {
+	words := strings.FieldsFunc(s, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	for _, w := range words {
+		if l.contains(w) {
+			return true
+		}
+	}
+	letters := strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) {
+			return r
+		}
+		return -1
+	}, s)
+	return l.contains(letters)
+}
+
+// charsets are the character sets the charset rule can require
+var charsets = map[string]func(r rune) bool{
+	// username allows ascii letters, digits, underscores, dashes and dots
+	"username": func(r rune) bool {
+		return r <= unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r) || strings.ContainsRune("_-.", r))
+	},
+	// name allows letters and digits of any script, spaces and some punctuation
+	"name": func(r rune) bool {
+		return unicode.IsLetter(r) || unicode.IsDigit(r) || strings.ContainsRune(" _-.'", r)
+	},
+}
+
+// validEmail reports whether s is a bare email address with a dotted domain
+func validEmail(s string) bool {
+	addr, err := mail.ParseAddress(s)
+	if err != nil || addr.Address != s {
+		return false
+	}
+	at := strings.LastIndex(s, "@")
+	return strings.Contains(s[at+1:], ".")
+}
+
+// Rules are what request fields are checked against, each Server has its own
+// and makes them available to Bind through the request context
+// Profanity holds words that may not appear in names
+type Rules struct {
+	Passwords *PasswordPolicy
+	Profanity wordList
+}
+
+const rulesKey contextKey = "rules"
+
+// NewRules creates rules with the default password policy and no profanity list
+func NewRules() *Rules {
+	return &Rules{Passwords: NewPasswordPolicy(), Profanity: wordList{}}
+}
+
+// WithRules is middleware that makes rules available to rulesFromRequest
+func WithRules(rules *Rules) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), rulesKey, rules)))
+		})
+	}
+}
+
+// rulesFromRequest returns the rules of the server handling r, or the default rules
+// for requests that did not go through WithRules
+func rulesFromRequest(r *http.Request) *Rules {
+	if rules, ok := r.Context().Value(rulesKey).(*Rules); ok {
+		return rules
+	}
+	return NewRules()
+}
+
+// Validate evaluates the validate tags of the struct v points to and adds every
+// field that fails to errs, fields are named by their json tag
+//
+// rules are separated by commas and checked in order, stopping at the first failure:
+// required, min=n, max=n, charset=name, trimmed, email, notreserved and clean
+// min and max bound the length of strings and the value of integers
+// rules other than required are skipped for zero values
+func (rules *Rules) Validate(v interface{}, errs *ValidationError) {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() == reflect.Ptr {
+		if rv.IsNil() {
+			return
+		}
+		rv = rv.Elem()
+	}
+	rt := rv.Type()
+	for i := 0; i < rt.NumField(); i++ {
+		field := rt.Field(i)
+		tag := field.Tag.Get("validate")
+		if tag == "" {
+			continue
+		}
+		value := rv.Field(i)
+		for _, rule := range strings.Split(tag, ",") {
+			if rule != "required" && value.IsZero() {
+				continue
+			}
+			if message := rules.checkRule(rule, value); message != "" {
+				errs.Add(jsonName(field), message)
+				break
+			}
+		}
+	}
+}
+
+// jsonName returns the name of a struct field in json
+func jsonName(field reflect.StructField) string {
+	name := strings.Split(field.Tag.Get("json"), ",")[0]
+	if name == "" || name == "-" {
+		return field.Name
+	}
+	return name
+}
+
+// checkRule returns a message if value breaks rule and an empty string otherwise
+func (rules *Rules) checkRule(rule string, value reflect.Value) string {
+	name, arg := rule, ""
+	if i := strings.Index(rule, "="); i >= 0 {
+		name, arg = rule[:i], rule[i+1:]
+	}
+	switch name {
+	case "required":
+		if value.IsZero() {
+			return "is required"
+		}
+	case "min", "max":
+		n, err := strconv.Atoi(arg)
+		if err != nil {
+			panic("validate: " + rule + " needs a number")
+		}
+		return checkBound(name, n, value)
+	case "charset":
+		allowed, ok := charsets[arg]
+		if !ok {
+			panic("validate: unknown charset " + arg)
+		}
+		for _, r := range value.String() {
+			if !allowed(r) {
+				return fmt.Sprintf("cannot contain %q", r)
+			}
+		}
+	case "trimmed":
+		s := value.String()
+		if strings.TrimSpace(s) != s || strings.Contains(s, "  ") {
+			return "cannot start or end with spaces or contain repeated spaces"
+		}
+	case "email":
+		if !validEmail(value.String()) {
+			return "is not a valid email address"
+		}
+	case "notreserved":
+		if reservedNames.contains(value.String()) {
+			return "is reserved"
+		}
+	case "clean":
+		if rules.Profanity.matches(value.String()) {
+			return "contains a word that is not allowed"
+		}
+	default:
+		panic("validate: unknown rule " + rule)
+	}
+	return ""
+}
+
+// checkBound checks a min or max rule against the length of a string or the value of an integer
+func checkBound(name string, n int, value reflect.Value) string {
+	switch value.Kind() {
+	case reflect.String:
+		length := utf8.RuneCountInString(value.String())
+		if name == "min" && length < n {
+			return fmt.Sprintf("must be at least %d characters", n)
+		}
+		if name == "max" && length > n {
+			return fmt.Sprintf("must be at most %d characters", n)
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if name == "min" && value.Int() < int64(n) {
+			return fmt.Sprintf("must be at least %d", n)
+		}
+		if name == "max" && value.Int() > int64(n) {
+			return fmt.Sprintf("must be at most %d", n)
+		}
+	default:
+		panic("validate: " + name + " does not apply to " + value.Kind().String())
+	}
+	return ""
+}